@@ -0,0 +1,94 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/travishegner/goi3status/types"
+)
+
+// readClicks parses the infinite array of click events which i3bar sends on
+// stdin when click_events is enabled, and routes each one to its module
+func (s *Status) readClicks(r io.Reader) {
+	dec := json.NewDecoder(r)
+	tok, err := dec.Token()
+	if err != nil {
+		log.Errorf("failed to read click events: %v", err)
+		return
+	}
+	if d, ok := tok.(json.Delim); !ok || d != '[' {
+		log.Errorf("unexpected start of click event stream: %v", tok)
+		return
+	}
+
+	for dec.More() {
+		ce := &types.ClickEvent{}
+		err = dec.Decode(ce)
+		if err != nil {
+			log.Errorf("failed to decode click event: %v", err)
+			return
+		}
+		s.routeClick(ce)
+	}
+}
+
+// routeClick uses the prefix added to each block's name by stampBlocks to
+// find the module and block index which was clicked, then removes it so
+// that the module sees its own name
+func (s *Status) routeClick(ce *types.ClickEvent) {
+	parts := strings.SplitN(ce.Name, "/", 3)
+	if len(parts) != 3 {
+		log.Warnf("click event for unknown module: %v", ce.Name)
+		return
+	}
+	id, err := strconv.Atoi(parts[0])
+	if err != nil {
+		log.Warnf("click event for unknown module: %v", ce.Name)
+		return
+	}
+	bi, err := strconv.Atoi(parts[1])
+	if err != nil {
+		log.Warnf("click event for unknown block: %v", ce.Name)
+		return
+	}
+	ce.Name = parts[2]
+	ce.Block = bi
+
+	// entries are found by id rather than position, as a reload may have
+	// moved or removed the module since the frame which was clicked
+	var mod types.Module
+	s.mu.Lock()
+	for _, e := range s.entries {
+		if e.id == id {
+			mod = e.module
+		}
+	}
+	s.mu.Unlock()
+	if mod == nil {
+		log.Warnf("click event for a module which has gone: %v", id)
+		return
+	}
+
+	c, ok := mod.(types.Clickable)
+	if !ok {
+		return
+	}
+	c.Click(ce)
+}
+
+// stampBlocks returns copies of the blocks of the entry with the given id,
+// with the id and block index prefixed to each name so that i3bar click
+// events can be routed back. The name and instance the module set are kept.
+func stampBlocks(id int, blocks []*types.Block) []*types.Block {
+	stamped := make([]*types.Block, len(blocks))
+	for i, b := range blocks {
+		c := *b
+		c.Name = fmt.Sprintf("%v/%v/%v", id, i, b.Name)
+		stamped[i] = &c
+	}
+	return stamped
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/travishegner/goi3status/types"
+)
+
+// clickModule records the clicks routed to it
+type clickModule struct {
+	*types.BaseModule
+	clicks []*types.ClickEvent
+}
+
+func (m *clickModule) MakeBlocks() []*types.Block         { return nil }
+func (m *clickModule) GetUpdateChan() chan []*types.Block { return m.Update }
+func (m *clickModule) Stop()                              {}
+func (m *clickModule) Click(ce *types.ClickEvent)         { m.clicks = append(m.clicks, ce) }
+
+func TestRouteClick(t *testing.T) {
+	a := &clickModule{BaseModule: types.NewBaseModule()}
+	b := &clickModule{BaseModule: types.NewBaseModule()}
+	s := &Status{entries: []*entry{{id: 3, module: a}, {id: 7, module: b}}}
+
+	blocks := stampBlocks(7, []*types.Block{
+		{FullText: "first", Name: "mail", Instance: "inbox"},
+		{FullText: "second"},
+	})
+	if blocks[0].Instance != "inbox" {
+		t.Errorf("stampBlocks changed the instance to %q", blocks[0].Instance)
+	}
+
+	s.routeClick(&types.ClickEvent{Name: blocks[0].Name, Instance: blocks[0].Instance, Button: 1})
+	s.routeClick(&types.ClickEvent{Name: blocks[1].Name, Button: 3})
+	if len(a.clicks) != 0 || len(b.clicks) != 2 {
+		t.Fatalf("routed %v clicks to a and %v to b, want 0 and 2", len(a.clicks), len(b.clicks))
+	}
+	if ce := b.clicks[0]; ce.Name != "mail" || ce.Instance != "inbox" || ce.Block != 0 {
+		t.Errorf("first click = %q %q block %v, want mail inbox block 0", ce.Name, ce.Instance, ce.Block)
+	}
+	if ce := b.clicks[1]; ce.Name != "" || ce.Block != 1 {
+		t.Errorf("second click = %q block %v, want no name and block 1", ce.Name, ce.Block)
+	}
+
+	// after a reload removes the entry, its clicks go nowhere
+	s.entries = s.entries[:1]
+	s.routeClick(&types.ClickEvent{Name: blocks[0].Name, Button: 1})
+	if len(a.clicks) != 0 || len(b.clicks) != 2 {
+		t.Errorf("click for a removed entry was routed")
+	}
+}
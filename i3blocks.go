@@ -0,0 +1,164 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/travishegner/goi3status/types"
+)
+
+// i3blocksProperty matches a key=value line of an i3blocks config
+var i3blocksProperty = regexp.MustCompile(`^([A-Za-z_][A-Za-z0-9_]*)\s*=\s*(.*)$`)
+
+// isI3blocksConfig reports whether a config file is in the INI format of
+// i3blocks, rather than yaml, judging by its first meaningful line
+func isI3blocksConfig(data []byte) bool {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		return strings.HasPrefix(line, "[") || i3blocksProperty.MatchString(line)
+	}
+	return false
+}
+
+// i3blocksBlock is a single section of an i3blocks config
+type i3blocksBlock struct {
+	name  string
+	props map[string]string
+}
+
+// parseI3blocksConfig translates an i3blocks config into ShellCommand
+// modules. Properties before the first section are defaults for every
+// block. Relative commands are run from dir, the config file's directory.
+func parseI3blocksConfig(data []byte, dir string) (*types.Config, error) {
+	global := make(map[string]string)
+	blocks := make([]*i3blocksBlock, 0)
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	n := 0
+	for scanner.Scan() {
+		n++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			b := &i3blocksBlock{
+				name:  strings.TrimSpace(line[1 : len(line)-1]),
+				props: make(map[string]string),
+			}
+			for k, v := range global {
+				b.props[k] = v
+			}
+			blocks = append(blocks, b)
+			continue
+		}
+
+		m := i3blocksProperty.FindStringSubmatch(line)
+		if m == nil {
+			return nil, fmt.Errorf("line %v: expected a [section] or key=value, got %q", n, line)
+		}
+		if len(blocks) == 0 {
+			global[m[1]] = m[2]
+		} else {
+			blocks[len(blocks)-1].props[m[1]] = m[2]
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	// i3blocks always asks for click events
+	c := &types.Config{ClickEvents: true}
+	for _, b := range blocks {
+		mc, err := i3blocksModule(b, dir)
+		if err != nil {
+			return nil, fmt.Errorf("block %v: %v", b.name, err)
+		}
+		c.Modules = append(c.Modules, map[interface{}]interface{}{
+			"name":   "ShellCommand",
+			"config": mc,
+		})
+	}
+	return c, nil
+}
+
+// i3blocksModule returns the ShellCommand config equivalent to an i3blocks
+// block. Like i3blocks, every property is also passed to the command as an
+// environment variable.
+func i3blocksModule(b *i3blocksBlock, dir string) (map[interface{}]interface{}, error) {
+	env := make(map[interface{}]interface{})
+	mc := map[interface{}]interface{}{
+		"shell":         "/bin/sh",
+		"cwd":           dir,
+		"block_name":    b.name,
+		"output_format": "i3blocks",
+		"env":           env,
+		// without an interval, a block only runs again on a click or signal
+		"once": true,
+	}
+
+	for k, v := range b.props {
+		env[k] = v
+		switch k {
+		case "command":
+			mc["cmd"] = v
+		case "interval":
+			delete(mc, "once")
+			switch v {
+			case "once", "-1", "0":
+				mc["once"] = true
+			case "persist", "-3":
+				mc["persist"] = true
+			case "repeat", "-2":
+				mc["repeat"] = true
+			default:
+				secs, err := strconv.Atoi(v)
+				if err != nil || secs < 0 {
+					return nil, fmt.Errorf("invalid interval %q", v)
+				}
+				mc["refresh"] = secs * 1000
+			}
+		case "signal":
+			sig, err := strconv.Atoi(v)
+			if err != nil || sig < 1 || sig > sigRTMax-sigRTMin {
+				return nil, fmt.Errorf("invalid signal %q", v)
+			}
+			mc["signal"] = sig
+		case "format":
+			if v == "json" {
+				mc["output_format"] = "json"
+			}
+		case "label":
+			// i3blocks puts the label before the text, not in a block of its own
+			mc["prefix"] = v
+		case "color", "markup", "instance":
+			mc[k] = v
+		case "separator":
+			mc["final_separator"] = v != "false"
+		case "separator_block_width":
+			w, err := strconv.Atoi(v)
+			if err != nil {
+				return nil, fmt.Errorf("invalid separator_block_width %q", v)
+			}
+			mc["final_separator_width"] = w
+		}
+	}
+
+	// a block without a command is static text, which the label can show
+	if _, ok := mc["cmd"]; !ok {
+		prefix, _ := mc["prefix"].(string)
+		mc["label"] = prefix + b.props["full_text"]
+		delete(mc, "prefix")
+	}
+
+	return mc, nil
+}
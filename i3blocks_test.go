@@ -0,0 +1,106 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestIsI3blocksConfig(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want bool
+	}{
+		{"section", "[time]\ncommand=date\n", true},
+		{"global property first", "# defaults\n\ninterval=5\n[time]\n", true},
+		{"yaml", "# modules\nmodules:\n  - name: Clock\n", false},
+		{"yaml with a space", "click_events: true\n", false},
+		{"only comments", "# nothing\n\n", false},
+		{"empty", "", false},
+	}
+	for _, tt := range tests {
+		if got := isI3blocksConfig([]byte(tt.data)); got != tt.want {
+			t.Errorf("%v: isI3blocksConfig() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestParseI3blocksConfig(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		// want are the settings expected of the only block
+		want map[interface{}]interface{}
+	}{
+		{
+			"no interval",
+			"[a]\ncommand=date\n",
+			map[interface{}]interface{}{"cmd": "date", "once": true},
+		},
+		{
+			"global properties",
+			"command=date\ninterval=5\ncolor=#ffffff\n\n[a]\ncolor=#ff0000\n",
+			map[interface{}]interface{}{"cmd": "date", "refresh": 5000, "color": "#ff0000"},
+		},
+		{
+			"comments and blank lines",
+			"# a comment\n\n[a]\n  # indented\ncommand = date\n\n",
+			map[interface{}]interface{}{"cmd": "date", "once": true},
+		},
+		{"once", "[a]\ncommand=date\ninterval=once\n", map[interface{}]interface{}{"once": true}},
+		{"-1", "[a]\ncommand=date\ninterval=-1\n", map[interface{}]interface{}{"once": true}},
+		{"0", "[a]\ncommand=date\ninterval=0\n", map[interface{}]interface{}{"once": true}},
+		{"persist", "[a]\ncommand=date\ninterval=persist\n", map[interface{}]interface{}{"persist": true}},
+		{"-3", "[a]\ncommand=date\ninterval=-3\n", map[interface{}]interface{}{"persist": true}},
+		{"repeat", "[a]\ncommand=date\ninterval=repeat\n", map[interface{}]interface{}{"repeat": true}},
+		{"-2", "[a]\ncommand=date\ninterval=-2\n", map[interface{}]interface{}{"repeat": true}},
+		{"lowest signal", "[a]\ncommand=date\nsignal=1\n", map[interface{}]interface{}{"signal": 1}},
+		{"highest signal", "[a]\ncommand=date\nsignal=30\n", map[interface{}]interface{}{"signal": 30}},
+		{
+			"label",
+			"[a]\ncommand=date\nlabel=T:\n",
+			map[interface{}]interface{}{"prefix": "T:", "label": nil},
+		},
+		{
+			"static text",
+			"[a]\nlabel=T:\nfull_text=hello\n",
+			map[interface{}]interface{}{"label": "T:hello", "prefix": nil, "cmd": nil},
+		},
+	}
+	for _, tt := range tests {
+		c, err := parseI3blocksConfig([]byte(tt.data), "/etc")
+		if err != nil {
+			t.Errorf("%v: parseI3blocksConfig() failed: %v", tt.name, err)
+			continue
+		}
+		if len(c.Modules) != 1 {
+			t.Errorf("%v: got %v modules, want 1", tt.name, len(c.Modules))
+			continue
+		}
+		mc := c.Modules[0]["config"].(map[interface{}]interface{})
+		if mc["block_name"] != "a" || mc["cwd"] != "/etc" {
+			t.Errorf("%v: block_name %q and cwd %q, want a and /etc", tt.name, mc["block_name"], mc["cwd"])
+		}
+		for k, v := range tt.want {
+			if !reflect.DeepEqual(mc[k], v) {
+				t.Errorf("%v: %v = %#v, want %#v", tt.name, k, mc[k], v)
+			}
+		}
+	}
+}
+
+func TestParseI3blocksConfigErrors(t *testing.T) {
+	tests := []string{
+		"[a]\nnot a property\n",
+		"[a]\ninterval=soon\n",
+		"[a]\ninterval=-4\n",
+		"[a]\nsignal=0\n",
+		"[a]\nsignal=31\n",
+		"[a]\nseparator_block_width=wide\n",
+	}
+	for _, data := range tests {
+		if _, err := parseI3blocksConfig([]byte(data), ""); err == nil {
+			t.Errorf("parseI3blocksConfig(%q) succeeded, want an error", data)
+		}
+	}
+}
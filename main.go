@@ -2,48 +2,143 @@ package main
 
 import (
 	"flag"
+	"fmt"
 	"io/ioutil"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"syscall"
+	"time"
 
 	log "github.com/sirupsen/logrus"
+	"github.com/travishegner/goi3status/outputs"
 	"github.com/travishegner/goi3status/types"
 	"gopkg.in/yaml.v2"
 )
 
+// how long to wait for modules to finish their current poll when exiting
+const stopTimeout = 5 * time.Second
+
+// the real-time signals on linux, as seen by programs using glibc. Modules
+// configured with signal N are refreshed by SIGRTMIN+N.
+const (
+	sigRTMin = 34
+	sigRTMax = 64
+)
+
 func main() {
 	cf := flag.String("config", "config.yaml", "config file describing status layout")
+	watch := flag.Bool("watch", false, "reload the config file whenever it changes")
 	flag.Parse()
 
-	conf, err := ioutil.ReadFile(*cf)
+	c, err := readConfig(*cf)
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+
+	stopSig := syscall.Signal(c.StopSignal)
+	contSig := syscall.Signal(c.ContSignal)
+
+	done := make(chan error)
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP, stopSig, contSig)
+	for rt := sigRTMin; rt <= sigRTMax; rt++ {
+		if rt != int(stopSig) && rt != int(contSig) {
+			signal.Notify(sig, syscall.Signal(rt))
+		}
+	}
+
+	// one pending reload is enough, however many times the file changes
+	reload := make(chan struct{}, 1)
+	if *watch {
+		err = watchConfig(*cf, reload)
+		if err != nil {
+			log.Errorf("failed to watch config file: %v", err)
+		}
+	}
+
+	out, err := outputs.GetOutput(c.Output)
 	if err != nil {
-		log.Fatalf("error reading config file: %v", err.Error())
+		log.Fatalf("%v", err)
 	}
 
-	c := types.Config{}
-	err = yaml.Unmarshal(conf, &c)
+	status := NewStatus(c, out)
+
+	go func() {
+		for {
+			select {
+			case s := <-sig:
+				switch s {
+				case stopSig:
+					status.Pause()
+				case contSig:
+					status.Resume()
+				case syscall.SIGHUP:
+					reloadConfig(status, *cf)
+				default:
+					if rt, ok := s.(syscall.Signal); ok && rt >= sigRTMin && rt <= sigRTMax {
+						status.Signal(int(rt) - sigRTMin)
+						continue
+					}
+					done <- status.Stop(stopTimeout)
+					return
+				}
+			case <-reload:
+				reloadConfig(status, *cf)
+			}
+		}
+	}()
+
+	err = <-done
 	if err != nil {
-		log.Fatalf("error unmarshalling config: %v", err)
+		log.Errorf("failed to stop cleanly: %v", err)
 		os.Exit(1)
 	}
+}
+
+func readConfig(path string) (*types.Config, error) {
+	conf, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading config file: %v", err)
+	}
+
+	c := &types.Config{}
+	if isI3blocksConfig(conf) {
+		c, err = parseI3blocksConfig(conf, filepath.Dir(path))
+		if err != nil {
+			return nil, fmt.Errorf("error parsing i3blocks config: %v", err)
+		}
+	} else {
+		err = yaml.Unmarshal(conf, c)
+		if err != nil {
+			return nil, fmt.Errorf("error unmarshalling config: %v", err)
+		}
+	}
 	// This software supports version 1 of the i3bar protocol
 	// https://i3wm.org/docs/i3bar-protocol.html
 	c.Version = 1
 
-	done := make(chan struct{})
-	sig := make(chan os.Signal)
-	signal.Notify(sig, syscall.SIGINT, syscall.SIGTERM)
+	if c.Output == "" {
+		c.Output = "i3bar"
+	}
 
-	status := NewStatus(&c)
+	// i3bar defaults to SIGSTOP/SIGCONT, which can't be trapped, so ask
+	// for catchable substitutes and pause the modules ourselves
+	if c.StopSignal == 0 {
+		c.StopSignal = int(syscall.SIGUSR1)
+	}
+	if c.ContSignal == 0 {
+		c.ContSignal = int(syscall.SIGUSR2)
+	}
 
-	go func() {
-		select {
-		case <-sig:
-			status.Stop()
-			close(done)
-		}
-	}()
+	return c, nil
+}
 
-	<-done
+func reloadConfig(status *Status, path string) {
+	c, err := readConfig(path)
+	if err != nil {
+		log.Errorf("not reloading: %v", err)
+		return
+	}
+	status.Reload(c)
 }
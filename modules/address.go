@@ -0,0 +1,244 @@
+package modules
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/travishegner/goi3status/types"
+)
+
+func init() {
+	addModMap("Address", NewAddress)
+}
+
+type addressConfig struct {
+	*types.BaseModuleConfig
+	Interface     string
+	Family        string
+	HideLinkLocal bool
+	CIDR          bool
+	format        *textFormat
+}
+
+// addressData is the data available to Address's format templates
+type addressData struct {
+	Interface string
+	// State is the operstate of the interface, e.g. up, down or dormant
+	State     string
+	Carrier   bool
+	MTU       int
+	IPv4      []string
+	IPv6      []string
+	Addresses []string
+}
+
+// Address is a module representing the addresses and link state of an interface
+type Address struct {
+	*types.BaseModule
+	config *addressConfig
+}
+
+func newAddressConfig(mc types.ModuleConfig) *addressConfig {
+	bmc := types.NewBaseModuleConfig(mc)
+
+	iface, ok := mc["interface"].(string)
+	if !ok {
+		iface = "auto"
+	}
+
+	family, ok := mc["family"].(string)
+	if !ok {
+		family = "all"
+	}
+
+	hideLL, ok := mc["hide_link_local"].(bool)
+	if !ok {
+		hideLL = true
+	}
+
+	cidr, ok := mc["cidr"].(bool)
+	if !ok {
+		cidr = false
+	}
+
+	return &addressConfig{
+		BaseModuleConfig: bmc,
+		Interface:        iface,
+		Family:           family,
+		HideLinkLocal:    hideLL,
+		CIDR:             cidr,
+		format:           newTextFormat(mc),
+	}
+}
+
+// NewAddress returns the Address module
+func NewAddress(mc types.ModuleConfig) types.Module {
+	config := newAddressConfig(mc)
+	bm := types.NewBaseModule()
+	a := &Address{
+		BaseModule: bm,
+		config:     config,
+	}
+
+	bm.Run(a.config.Refresh, a.MakeBlocks)
+
+	return a
+}
+
+// MakeBlocks returns the Block array for this module
+func (a *Address) MakeBlocks() []*types.Block {
+	b := make([]*types.Block, 0)
+	if a.config.Label != "" {
+		block := types.NewBlock(a.config.BlockSeparatorWidth)
+		block.FullText = a.config.Label
+		b = append(b, block)
+	}
+
+	block := types.NewBlock(a.config.FinalSeparatorWidth)
+	if a.config.FinalSeparator {
+		block.AddSeparator()
+	}
+
+	iface := a.config.Interface
+	if iface == "auto" {
+		var err error
+		iface, err = defaultRouteInterface("/proc/net/route")
+		if err != nil {
+			log.Warningf("failed to find the default route: %v", err.Error())
+			block.FullText = "no route"
+			a.config.Thresholds.Apply(block, "auto", 1)
+			b = append(b, block)
+			return b
+		}
+	}
+
+	data := a.readAddress(iface)
+	switch data.State {
+	case "down", "lowerlayerdown", "notpresent":
+		block.FullText = iface + " " + data.State
+		block.Urgent = true
+		a.config.Thresholds.Apply(block, iface, 1)
+	default:
+		block.FullText = strings.Join(data.Addresses, " ")
+		if block.FullText == "" {
+			block.FullText = iface + " " + data.State
+		}
+		a.config.Thresholds.Apply(block, iface, 0)
+	}
+	a.config.format.Apply(block, data)
+
+	b = append(b, block)
+	return b
+}
+
+// readAddress returns the link state and filtered addresses of iface
+func (a *Address) readAddress(iface string) *addressData {
+	dir := filepath.Join(sysfsRoot, "class/net", iface)
+	data := &addressData{
+		Interface: iface,
+		State:     readLine(filepath.Join(dir, "operstate")),
+		Addresses: make([]string, 0),
+	}
+	if data.State == "" {
+		data.State = "notpresent"
+		return data
+	}
+	// carrier can't be read while the interface is administratively down
+	carrier, err := readInt(filepath.Join(dir, "carrier"))
+	data.Carrier = err == nil && carrier == 1
+	mtu, _ := readInt(filepath.Join(dir, "mtu"))
+	data.MTU = int(mtu)
+
+	ni, err := net.InterfaceByName(iface)
+	if err != nil {
+		return data
+	}
+	addrs, err := ni.Addrs()
+	if err != nil {
+		log.Warningf("failed to get addresses of %v: %v", iface, err.Error())
+		return data
+	}
+
+	for _, addr := range addrs {
+		ipnet, ok := addr.(*net.IPNet)
+		if !ok {
+			continue
+		}
+		if a.config.HideLinkLocal && ipnet.IP.IsLinkLocalUnicast() {
+			continue
+		}
+
+		text := ipnet.IP.String()
+		if a.config.CIDR {
+			text = ipnet.String()
+		}
+
+		if ipnet.IP.To4() != nil {
+			data.IPv4 = append(data.IPv4, text)
+			if a.config.Family == "ipv6" {
+				continue
+			}
+		} else {
+			data.IPv6 = append(data.IPv6, text)
+			if a.config.Family == "ipv4" {
+				continue
+			}
+		}
+		data.Addresses = append(data.Addresses, text)
+	}
+
+	return data
+}
+
+// GetUpdateChan returns the channel down which new block arrays are sent
+func (a *Address) GetUpdateChan() chan []*types.Block {
+	return a.Update
+}
+
+// Stop stops this module from polling and sending updated Block arrays
+func (a *Address) Stop() {
+	close(a.Done)
+}
+
+// defaultRouteInterface returns the interface holding the default route with
+// the lowest metric, from a file in the format of /proc/net/route
+func defaultRouteInterface(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	iface := ""
+	best := int64(-1)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		// Iface Destination Gateway Flags RefCnt Use Metric Mask ...
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 8 || fields[1] != "00000000" || fields[7] != "00000000" {
+			continue
+		}
+		metric, err := strconv.ParseInt(fields[6], 10, 64)
+		if err != nil {
+			continue
+		}
+		if best < 0 || metric < best {
+			iface = fields[0]
+			best = metric
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+
+	if iface == "" {
+		return "", fmt.Errorf("no default route in %v", path)
+	}
+	return iface, nil
+}
@@ -2,10 +2,13 @@ package modules
 
 import (
 	"fmt"
+	"os/exec"
+	"strconv"
 	"time"
 
 	"github.com/distatus/battery"
 	log "github.com/sirupsen/logrus"
+	"github.com/travishegner/goi3status/notify"
 	"github.com/travishegner/goi3status/types"
 )
 
@@ -16,12 +19,50 @@ func init() {
 type batteryConfig struct {
 	*types.BaseModuleConfig
 	Attribute string
+	Combined  bool
+	// Warning, Critical and Suspend are the percentages at or below which a
+	// discharging battery alerts, with 0 disabling the alert
+	Warning        int
+	Critical       int
+	Suspend        int
+	SuspendCommand string
+	Notify         bool
+	format         *textFormat
+	history        *sparkline
+}
+
+// the alert levels of a battery, each worse than the last
+const (
+	batteryOK = iota
+	batteryWarning
+	batteryCritical
+	batterySuspend
+)
+
+// batteryRearm is how many percent a discharging battery must rise above
+// an alert's level before that alert can fire again, so that a reading
+// wavering around the level doesn't repeat it
+const batteryRearm = 3
+
+// batteryData is the data available to Battery's format templates
+type batteryData struct {
+	Index   int
+	Percent float64
+	State   string
+	// TimeToEmpty is set while discharging, and TimeToFull while charging
+	TimeToEmpty time.Duration
+	TimeToFull  time.Duration
+	// Watts is the rate of charge or discharge
+	Watts float64
+	// Health is the full capacity as a percentage of the design capacity
+	Health float64
 }
 
 // Battery is a module representing the any machine batteries
 type Battery struct {
 	*types.BaseModule
-	config *batteryConfig
+	config    *batteryConfig
+	crossings *notify.Crossings
 }
 
 func newBatteryConfig(mc types.ModuleConfig) *batteryConfig {
@@ -31,9 +72,52 @@ func newBatteryConfig(mc types.ModuleConfig) *batteryConfig {
 		attribute = "percent"
 	}
 
+	combined, ok := mc["combined"].(bool)
+	if !ok {
+		combined = false
+	}
+
+	warning, ok := mc["warning"].(int)
+	if !ok {
+		warning = 15
+	}
+
+	critical, ok := mc["critical"].(int)
+	if !ok {
+		critical = 5
+	}
+
+	suspend, ok := mc["suspend"].(int)
+	if !ok {
+		suspend = 0
+	}
+
+	suspendCmd, ok := mc["suspend_command"].(string)
+	if !ok {
+		suspendCmd = "systemctl suspend"
+	}
+
+	notifyOn, ok := mc["notify"].(bool)
+	if !ok {
+		notifyOn = true
+	}
+
+	// a low charge is bad, so the scale runs the other way by default
+	if _, ok := mc["invert"].(bool); !ok {
+		bmc.Thresholds.Invert = true
+	}
+
 	return &batteryConfig{
 		BaseModuleConfig: bmc,
 		Attribute:        attribute,
+		Combined:         combined,
+		Warning:          warning,
+		Critical:         critical,
+		Suspend:          suspend,
+		SuspendCommand:   suspendCmd,
+		Notify:           notifyOn,
+		format:           newTextFormat(mc),
+		history:          newSparkline(mc),
 	}
 
 }
@@ -45,21 +129,10 @@ func NewBattery(mc types.ModuleConfig) types.Module {
 	bat := &Battery{
 		BaseModule: bm,
 		config:     config,
+		crossings:  notify.NewCrossings(),
 	}
 
-	bm.Update <- bat.MakeBlocks()
-	ticker := time.NewTicker(bat.config.Refresh)
-
-	go func() {
-		for {
-			select {
-			case <-bm.Done:
-				return
-			case <-ticker.C:
-				bm.Update <- bat.MakeBlocks()
-			}
-		}
-	}()
+	bm.Run(bat.config.Refresh, bat.MakeBlocks)
 
 	return bat
 }
@@ -91,39 +164,197 @@ func (bat *Battery) MakeBlocks() []*types.Block {
 		goodBats = append(goodBats, tb)
 	}
 
+	if bat.config.Combined && len(goodBats) > 0 {
+		goodBats = []*battery.Battery{combineBatteries(goodBats)}
+	}
+
 	for i, tb := range goodBats {
-		text := ""
-		color := ""
-		switch bat.config.Attribute {
-		case "percent":
-			text = fmt.Sprintf("%v", int((tb.Current/tb.Full)*100))
-			color = GetColor(1.0 - (tb.Current / tb.Full))
-		case "state":
-			switch tb.State.String() {
-			case "Discharging":
-				text = "🔋"
-				if int((tb.Current/tb.Full)*100) < 25 {
-					text = "🪫"
-				}
-			default:
-				text = "🔌"
+		var bb []*types.Block
+		if bat.config.history.IsSet() {
+			bb = bat.config.history.Blocks(strconv.Itoa(i), (tb.Current/tb.Full)*100, 100, bat.config.BlockSeparatorWidth, bat.config.Thresholds)
+		} else {
+			bb = []*types.Block{bat.makeBlock(i, tb)}
+		}
+		if bat.alert(i, tb) >= batteryWarning {
+			for _, block := range bb {
+				block.Urgent = true
 			}
 		}
 
-		block := types.NewBlock(bat.config.BlockSeparatorWidth)
-		block.FullText = text
-		if color != "" {
-			block.Color = color
+		if i == len(goodBats)-1 {
+			block := bb[len(bb)-1]
+			block.SeparatorBlockWidth = bat.config.FinalSeparatorWidth
+			if bat.config.FinalSeparator {
+				block.AddSeparator()
+			}
 		}
-		block.SeparatorBlockWidth = bat.config.FinalSeparatorWidth
-		if i == len(batteries) && bat.config.FinalSeparator {
-			block.AddSeparator()
+		b = append(b, bb...)
+	}
+
+	return b
+}
+
+func (bat *Battery) makeBlock(i int, tb *battery.Battery) *types.Block {
+	block := types.NewBlock(bat.config.BlockSeparatorWidth)
+	data := &batteryData{
+		Index:   i,
+		Percent: (tb.Current / tb.Full) * 100,
+		State:   tb.State.String(),
+		Watts:   tb.ChargeRate / 1000,
+	}
+	if tb.Design > 0 {
+		data.Health = tb.Full / tb.Design * 100
+	}
+	// the charge rate is in mW and the capacities in mWh
+	if tb.ChargeRate > 0 {
+		switch tb.State {
+		case battery.Discharging:
+			data.TimeToEmpty = time.Duration(tb.Current / tb.ChargeRate * float64(time.Hour))
+		case battery.Charging:
+			data.TimeToFull = time.Duration((tb.Full - tb.Current) / tb.ChargeRate * float64(time.Hour))
 		}
+	}
 
-		b = append(b, block)
+	text := ""
+	switch bat.config.Attribute {
+	case "percent":
+		text = fmt.Sprintf("%v", int(data.Percent))
+		bat.config.Thresholds.Apply(block, strconv.Itoa(i), tb.Current/tb.Full)
+	case "state":
+		switch tb.State.String() {
+		case "Discharging":
+			text = "🔋"
+			if int(data.Percent) < 25 {
+				text = "🪫"
+			}
+		default:
+			text = "🔌"
+		}
+	case "time":
+		switch {
+		case data.TimeToEmpty > 0:
+			text = formatHoursMinutes(data.TimeToEmpty)
+		case data.TimeToFull > 0:
+			text = formatHoursMinutes(data.TimeToFull) + "+"
+		default:
+			text = tb.State.String()
+		}
+		bat.config.Thresholds.Apply(block, strconv.Itoa(i), tb.Current/tb.Full)
+	case "power":
+		text = fmt.Sprintf("%.1fW", data.Watts)
+	case "health":
+		text = fmt.Sprintf("%v%%", int(data.Health))
+		bat.config.Thresholds.Apply(block, strconv.Itoa(i), data.Health/100)
 	}
 
-	return b
+	block.FullText = text
+	bat.config.format.Apply(block, data)
+	return block
+}
+
+// alert returns the alert level of a discharging battery. The first time
+// the battery crosses into a level, a notification is sent, and at the
+// suspend level the suspend command is run. A level is kept until the
+// battery charges, or rises batteryRearm percent above it.
+func (bat *Battery) alert(i int, tb *battery.Battery) int {
+	key := strconv.Itoa(i)
+	pct := tb.Current / tb.Full * 100
+	level := batteryOK
+	switch tb.State {
+	case battery.Charging, battery.Full:
+		// charging re-arms every alert
+	case battery.Discharging:
+		for l := batterySuspend; l > batteryOK; l-- {
+			if limit := bat.alertLimit(l); limit > 0 && pct <= float64(limit) {
+				level = l
+				break
+			}
+		}
+		for l := bat.crossings.Level(key); l > level; l-- {
+			if limit := bat.alertLimit(l); limit > 0 && pct <= float64(limit+batteryRearm) {
+				level = l
+				break
+			}
+		}
+	default:
+		// an unknown state says nothing about whether the battery charged
+		level = bat.crossings.Level(key)
+	}
+
+	if !bat.crossings.Cross(key, level) {
+		return level
+	}
+
+	n := &notify.Notification{
+		Summary: "Battery low",
+		Body:    fmt.Sprintf("%v%% remaining", int(pct)),
+		Icon:    "battery-caution",
+		Urgency: notify.Normal,
+	}
+	if tb.ChargeRate > 0 {
+		n.Body += ", " + formatHoursMinutes(time.Duration(tb.Current/tb.ChargeRate*float64(time.Hour)))
+	}
+	switch level {
+	case batterySuspend:
+		n.Summary = "Battery exhausted, suspending"
+		n.Urgency = notify.Critical
+		bat.Go(func() {
+			out, err := exec.Command("/bin/sh", "-c", bat.config.SuspendCommand).CombinedOutput()
+			if err != nil {
+				log.Errorf("failed to run suspend command: %v: %s", err, out)
+			}
+		})
+	case batteryCritical:
+		n.Summary = "Battery critical"
+		n.Icon = "battery-empty"
+		n.Urgency = notify.Critical
+	}
+	if bat.config.Notify {
+		notify.Send(n)
+	}
+	return level
+}
+
+// alertLimit returns the percentage at or below which level alerts, or 0 if
+// it is disabled
+func (bat *Battery) alertLimit(level int) int {
+	switch level {
+	case batterySuspend:
+		return bat.config.Suspend
+	case batteryCritical:
+		return bat.config.Critical
+	case batteryWarning:
+		return bat.config.Warning
+	}
+	return 0
+}
+
+// combineBatteries returns a single battery with the capacity and charge
+// rate of all of them, which is charging if any of them are
+func combineBatteries(bats []*battery.Battery) *battery.Battery {
+	c := &battery.Battery{State: bats[0].State}
+	for _, b := range bats {
+		c.Current += b.Current
+		c.Full += b.Full
+		c.Design += b.Design
+		switch b.State {
+		case battery.Charging:
+			c.ChargeRate += b.ChargeRate
+			c.State = battery.Charging
+		case battery.Discharging:
+			c.ChargeRate += b.ChargeRate
+			if c.State != battery.Charging {
+				c.State = battery.Discharging
+			}
+		}
+	}
+	return c
+}
+
+// formatHoursMinutes formats d as h:mm
+func formatHoursMinutes(d time.Duration) string {
+	m := int(d.Round(time.Minute).Minutes())
+	return fmt.Sprintf("%d:%02d", m/60, m%60)
 }
 
 // GetUpdateChan returns the channel down which new block arrays are sent
@@ -0,0 +1,66 @@
+package modules
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/distatus/battery"
+	"github.com/travishegner/goi3status/notify"
+	"github.com/travishegner/goi3status/types"
+)
+
+func TestBatteryAlert(t *testing.T) {
+	runs := filepath.Join(t.TempDir(), "runs")
+	bat := &Battery{
+		BaseModule: types.NewBaseModule(),
+		config: newBatteryConfig(types.ModuleConfig{
+			"warning":         20,
+			"critical":        10,
+			"suspend":         5,
+			"suspend_command": "echo suspend >> " + runs,
+		}),
+		crossings: notify.NewCrossings(),
+	}
+
+	steps := []struct {
+		pct   float64
+		state battery.State
+		want  int
+	}{
+		{50, battery.Discharging, batteryOK},
+		{20, battery.Discharging, batteryWarning},
+		// wavering around a level keeps it, until well above it
+		{21, battery.Discharging, batteryWarning},
+		{23, battery.Discharging, batteryWarning},
+		{24, battery.Discharging, batteryOK},
+		{19, battery.Discharging, batteryWarning},
+		{10, battery.Discharging, batteryCritical},
+		{5, battery.Discharging, batterySuspend},
+		{6, battery.Discharging, batterySuspend},
+		{5, battery.Discharging, batterySuspend},
+		// an unknown state keeps the level
+		{5, battery.Unknown, batterySuspend},
+		// above the suspend level, but still critical
+		{9, battery.Discharging, batteryCritical},
+		// charging re-arms every level
+		{9, battery.Charging, batteryOK},
+		{5, battery.Discharging, batterySuspend},
+	}
+	for i, s := range steps {
+		tb := &battery.Battery{Current: s.pct, Full: 100, State: s.state}
+		if got := bat.alert(0, tb); got != s.want {
+			t.Errorf("step %v: alert() at %v%% = %v, want %v", i, s.pct, got, s.want)
+		}
+	}
+
+	bat.Wait()
+	out, err := os.ReadFile(runs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(string(out), "suspend"); n != 2 {
+		t.Errorf("the suspend command ran %v times, want 2", n)
+	}
+}
@@ -0,0 +1,230 @@
+package modules
+
+import (
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	log "github.com/sirupsen/logrus"
+	"github.com/travishegner/goi3status/types"
+)
+
+func init() {
+	addModMap("Brightness", NewBrightness)
+}
+
+type brightnessConfig struct {
+	*types.BaseModuleConfig
+	device     string
+	attribute  string
+	scrollUp   string
+	scrollDown string
+	format     *textFormat
+}
+
+// brightnessData is the data available to Brightness's format templates
+type brightnessData struct {
+	Device     string
+	Brightness int64
+	Max        int64
+	Percent    float64
+}
+
+// Brightness is a module representing the brightness of a backlight
+type Brightness struct {
+	*types.BaseModule
+	config *brightnessConfig
+}
+
+func newBrightnessConfig(mc types.ModuleConfig) *brightnessConfig {
+	bmc := types.NewBaseModuleConfig(mc)
+
+	// changes are watched for, so polling is only a fallback
+	if _, ok := mc["refresh"].(int); !ok {
+		bmc.Refresh = 30 * time.Second
+	}
+
+	device, ok := mc["device"].(string)
+	if !ok {
+		device = ""
+	}
+
+	attr, ok := mc["attribute"].(string)
+	if !ok {
+		attr = "percent"
+	}
+
+	// writing to sysfs usually needs privileges, so scrolling runs a
+	// command, with {device} replaced by the backlight's name
+	up, ok := mc["scroll_up"].(string)
+	if !ok {
+		up = "brightnessctl -q -d {device} set 5%+"
+	}
+
+	down, ok := mc["scroll_down"].(string)
+	if !ok {
+		down = "brightnessctl -q -d {device} set 5%-"
+	}
+
+	return &brightnessConfig{
+		BaseModuleConfig: bmc,
+		device:           device,
+		attribute:        attr,
+		scrollUp:         up,
+		scrollDown:       down,
+		format:           newTextFormat(mc),
+	}
+}
+
+// NewBrightness returns the Brightness module
+func NewBrightness(mc types.ModuleConfig) types.Module {
+	config := newBrightnessConfig(mc)
+	bm := types.NewBaseModule()
+	br := &Brightness{
+		BaseModule: bm,
+		config:     config,
+	}
+
+	bm.Run(br.config.Refresh, br.MakeBlocks)
+	if dir := br.deviceDir(); dir != "" {
+		br.watch(dir)
+	}
+
+	return br
+}
+
+// deviceDir returns the sysfs directory of the configured backlight, or the
+// first one found
+func (br *Brightness) deviceDir() string {
+	if br.config.device != "" {
+		return filepath.Join(sysfsRoot, "class/backlight", br.config.device)
+	}
+	devs, _ := filepath.Glob(filepath.Join(sysfsRoot, "class/backlight/*"))
+	if len(devs) == 0 {
+		return ""
+	}
+	return devs[0]
+}
+
+// watch refreshes the module whenever the brightness files change
+func (br *Brightness) watch(dir string) {
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		log.Warnf("failed to watch backlight: %v", err)
+		return
+	}
+
+	for _, f := range []string{"brightness", "actual_brightness"} {
+		if err := w.Add(filepath.Join(dir, f)); err != nil {
+			log.Warnf("failed to watch backlight: %v", err)
+		}
+	}
+
+	br.Go(func() {
+		defer w.Close()
+		for {
+			select {
+			case <-br.Done:
+				return
+			case _, ok := <-w.Events:
+				if !ok {
+					return
+				}
+				br.Refresh()
+			case err, ok := <-w.Errors:
+				if !ok {
+					return
+				}
+				log.Warnf("error watching backlight: %v", err)
+			}
+		}
+	})
+}
+
+// MakeBlocks returns the Block array for this module
+func (br *Brightness) MakeBlocks() []*types.Block {
+	b := make([]*types.Block, 0)
+	if br.config.Label != "" {
+		block := types.NewBlock(br.config.BlockSeparatorWidth)
+		block.FullText = br.config.Label
+		b = append(b, block)
+	}
+
+	dir := br.deviceDir()
+	if dir == "" {
+		log.Warnf("no backlight found")
+		return b
+	}
+
+	// actual_brightness is what the hardware reports, which may lag behind
+	// or differ from what was last requested
+	cur, err := readInt(filepath.Join(dir, "actual_brightness"))
+	if err != nil {
+		cur, err = readInt(filepath.Join(dir, "brightness"))
+	}
+	if err != nil {
+		log.Warnf("failed to read brightness of %v: %v", dir, err)
+		return b
+	}
+	max, err := readInt(filepath.Join(dir, "max_brightness"))
+	if err != nil || max <= 0 {
+		log.Warnf("failed to read max brightness of %v", dir)
+		return b
+	}
+
+	pct := float64(cur) / float64(max) * 100
+	block := types.NewBlock(br.config.FinalSeparatorWidth)
+	if br.config.FinalSeparator {
+		block.AddSeparator()
+	}
+	switch br.config.attribute {
+	case "bar":
+		block.FullText = sparkChar(pct / 100)
+	default:
+		block.FullText = fmt.Sprintf("%v%%", int(pct+0.5))
+	}
+	br.config.Thresholds.Apply(block, "brightness", pct/100)
+	br.config.format.Apply(block, &brightnessData{
+		Device:     filepath.Base(dir),
+		Brightness: cur,
+		Max:        max,
+		Percent:    pct,
+	})
+
+	b = append(b, block)
+	return b
+}
+
+// Click runs the scroll commands on scrolling up or down
+func (br *Brightness) Click(ce *types.ClickEvent) {
+	cmd := ""
+	switch ce.Button {
+	case 4:
+		cmd = br.config.scrollUp
+	case 5:
+		cmd = br.config.scrollDown
+	}
+	dir := br.deviceDir()
+	if cmd == "" || dir == "" {
+		return
+	}
+
+	cmd = strings.ReplaceAll(cmd, "{device}", filepath.Base(dir))
+	if out, err := exec.Command("/bin/sh", "-c", cmd).CombinedOutput(); err != nil {
+		log.Warnf("failed to change brightness: %v: %s", err, out)
+	}
+	br.Refresh()
+}
+
+// GetUpdateChan returns the channel down which new block arrays are sent
+func (br *Brightness) GetUpdateChan() chan []*types.Block {
+	return br.Update
+}
+
+// Stop stops this module from polling and sending updated Block arrays
+func (br *Brightness) Stop() {
+	close(br.Done)
+}
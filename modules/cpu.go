@@ -2,8 +2,8 @@ package modules
 
 import (
 	"fmt"
-	"path/filepath"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/shirou/gopsutil/cpu"
@@ -18,8 +18,13 @@ func init() {
 // CPU is a module to collect cpu information
 type CPU struct {
 	*types.BaseModule
-	config    *cpuConfig
-	graphChar []string
+	config *cpuConfig
+	times  *sampleSub
+	// lastTimes are the cpu times of the previous snapshot, taken at lastAt,
+	// and lastUtil are the blocks made from them
+	lastTimes []cpu.TimesStat
+	lastAt    time.Time
+	lastUtil  []*types.Block
 }
 
 type cpuConfig struct {
@@ -28,6 +33,18 @@ type cpuConfig struct {
 	average     bool
 	tempGreen   int64
 	tempRed     int64
+	// tempMax shows only the hottest package, rather than each of them
+	tempMax bool
+	format  *textFormat
+	history *sparkline
+}
+
+// cpuData is the data available to CPU's format templates, for a single block
+type cpuData struct {
+	// Core is the index of the core, or 0 when averaging
+	Core    int
+	Percent float64
+	Temp    int64
 }
 
 func newCPUConfig(mc types.ModuleConfig) *cpuConfig {
@@ -52,49 +69,36 @@ func newCPUConfig(mc types.ModuleConfig) *cpuConfig {
 		tempRed = 80
 	}
 
+	tempAgg, ok := mc["temp_aggregate"].(string)
+	if !ok {
+		tempAgg = ""
+	}
+
 	return &cpuConfig{
 		BaseModuleConfig: bmc,
 		monitorType:      mon,
 		average:          avg,
 		tempGreen:        int64(tempGreen),
 		tempRed:          int64(tempRed),
+		tempMax:          tempAgg == "max",
+		format:           newTextFormat(mc),
+		history:          newSparkline(mc),
 	}
 }
 
 // NewCPU returns a new CPU module
 func NewCPU(mc types.ModuleConfig) types.Module {
 	config := newCPUConfig(mc)
-
-	char := make([]string, 8)
-	char[0] = "\u2581"
-	char[1] = "\u2582"
-	char[2] = "\u2583"
-	char[3] = "\u2584"
-	char[4] = "\u2585"
-	char[5] = "\u2586"
-	char[6] = "\u2587"
-	char[7] = "\u2588"
-
 	bm := types.NewBaseModule()
 	cpuMod := &CPU{
 		BaseModule: bm,
 		config:     config,
-		graphChar:  char,
 	}
 
-	bm.Update <- cpuMod.MakeBlocks()
-	ticker := time.NewTicker(config.Refresh)
-
-	go func() {
-		for {
-			select {
-			case <-bm.Done:
-				return
-			case <-ticker.C:
-				bm.Update <- cpuMod.MakeBlocks()
-			}
-		}
-	}()
+	if config.monitorType != "temp" {
+		cpuMod.times = subscribe(cpuTimesSampler(!config.average), config.Refresh, bm.Refresh)
+	}
+	bm.Run(config.Refresh, cpuMod.MakeBlocks)
 
 	return cpuMod
 }
@@ -121,72 +125,123 @@ func (c *CPU) MakeBlocks() []*types.Block {
 
 func (c *CPU) makeTempBlocks() []*types.Block {
 	b := make([]*types.Block, 0)
-	zones, err := filepath.Glob("/sys/class/thermal/thermal_zone*")
-	if err != nil {
-		log.Warnf("error getting thermal zones")
+	sensors := cpuTempSensors(readTempSensors(sysfsRoot))
+	if len(sensors) == 0 {
+		log.Warnf("no cpu temperature sensors found")
 		return b
 	}
 
-	for _, z := range zones {
-		tpe := readLine(z + "/type")
-		if tpe != "x86_pkg_temp" {
-			continue
-		}
-		i32, _ := strconv.Atoi(readLine(z + "/temp"))
-		temp := int64(i32) / 1000
+	if c.config.tempMax {
+		sensors = []*tempSensor{maxSensor(sensors)}
+	}
+
+	for i, s := range sensors {
+		temp := int64(s.Temp)
 		block := types.NewBlock(c.config.BlockSeparatorWidth)
 		block.FullText = fmt.Sprintf("%v\u2103", temp)
+		c.config.format.Apply(block, &cpuData{Core: i, Temp: temp})
 		base := temp - c.config.tempGreen
 		if base < 0 {
 			base = 0
 		}
-		block.Color = GetColor(float64(base) / float64(c.config.tempRed-c.config.tempGreen))
+		c.config.Thresholds.Apply(block, s.Label, float64(base)/float64(c.config.tempRed-c.config.tempGreen))
 		b = append(b, block)
 	}
 
-	if len(b) > 0 {
-		block := b[len(b)-1]
-		block.SeparatorBlockWidth = c.config.FinalSeparatorWidth
-		if c.config.FinalSeparator {
-			block.AddSeparator()
-		}
+	block := b[len(b)-1]
+	block.SeparatorBlockWidth = c.config.FinalSeparatorWidth
+	if c.config.FinalSeparator {
+		block.AddSeparator()
 	}
 
 	return b
 }
 
+// cpuTempChips are the sensor chips which measure the cpu, most specific first
+var cpuTempChips = []string{"x86_pkg_temp", "k10temp", "zenpower", "coretemp", "cpu_thermal", "cpu-thermal"}
+
+// cpuPackageLabels are the labels of the sensors which measure a whole
+// package, for chips which also have a sensor per core or die
+var cpuPackageLabels = []string{"Package id", "Tdie", "Tctl"}
+
+// cpuTempSensors returns a sensor for each package of the most specific
+// cpu chip present
+func cpuTempSensors(sensors []*tempSensor) []*tempSensor {
+	for _, chip := range cpuTempChips {
+		f := make([]*tempSensor, 0)
+		for _, s := range sensors {
+			if s.Chip == chip {
+				f = append(f, s)
+			}
+		}
+		if len(f) == 0 {
+			continue
+		}
+
+		for _, prefix := range cpuPackageLabels {
+			pkgs := make([]*tempSensor, 0)
+			for _, s := range f {
+				if strings.HasPrefix(s.Label, prefix) {
+					pkgs = append(pkgs, s)
+				}
+			}
+			if len(pkgs) > 0 {
+				return pkgs
+			}
+		}
+		return f
+	}
+	return nil
+}
+
 func (c *CPU) makeUtilBlocks() []*types.Block {
 	b := make([]*types.Block, 0)
 
-	cpus, err := cpu.Percent(c.config.Refresh, !c.config.average)
-	if err != nil {
-		log.Warnf("err getting cpu percentages: %v", err)
+	snap := c.times.Latest()
+	if snap.err != nil {
+		log.Warnf("err getting cpu times: %v", snap.err)
+		return b
+	}
+	// a refresh between snapshots has nothing new to show
+	if snap.at.Equal(c.lastAt) && c.lastUtil != nil {
+		return c.lastUtil
 	}
+	times := snap.value.([]cpu.TimesStat)
+	cpus := cpuPercents(c.lastTimes, times)
+	c.lastTimes = times
+	c.lastAt = snap.at
 
 	for i, v := range cpus {
-		block := c.getUtilBlock(v)
-		if i == len(cpus)-1 {
-			block.SeparatorBlockWidth = c.config.FinalSeparatorWidth
-			if c.config.FinalSeparator {
-				block.AddSeparator()
-			}
+		if c.config.history.IsSet() {
+			b = append(b, c.config.history.Blocks(strconv.Itoa(i), v, 100, c.config.BlockSeparatorWidth, c.config.Thresholds)...)
+			continue
+		}
+		b = append(b, c.getUtilBlock(i, v))
+	}
+
+	if len(b) > 0 {
+		block := b[len(b)-1]
+		block.SeparatorBlockWidth = c.config.FinalSeparatorWidth
+		if c.config.FinalSeparator {
+			block.AddSeparator()
 		}
-		b = append(b, block)
 	}
+	c.lastUtil = b
 	return b
 }
 
-func (c *CPU) getUtilBlock(val float64) *types.Block {
+func (c *CPU) getUtilBlock(core int, val float64) *types.Block {
 	block := types.NewBlock(c.config.BlockSeparatorWidth)
 	switch c.config.monitorType {
 	case "graph":
-		block.FullText = fmt.Sprintf("%v", c.graphChar[int((val/100)*7)])
+		block.FullText = sparkChar(val / 100)
 	case "percent":
 		block.FullText = fmt.Sprintf("%v", int(val))
 		block.MinWidth = "99"
 		block.Align = "right"
 	}
-	block.Color = GetColor(val / 100)
+	c.config.Thresholds.Apply(block, strconv.Itoa(core), val/100)
+	c.config.format.Apply(block, &cpuData{Core: core, Percent: val})
 
 	return block
 }
@@ -199,4 +254,7 @@ func (c *CPU) GetUpdateChan() chan []*types.Block {
 // Stop stops the module
 func (c *CPU) Stop() {
 	close(c.Done)
+	if c.times != nil {
+		c.times.Close()
+	}
 }
@@ -55,19 +55,7 @@ func NewDateTime(mc types.ModuleConfig) types.Module {
 		config:     config,
 	}
 
-	bm.Update <- dt.MakeBlocks()
-	ticker := time.NewTicker(dt.config.Refresh)
-
-	go func() {
-		for {
-			select {
-			case <-bm.Done:
-				return
-			case <-ticker.C:
-				bm.Update <- dt.MakeBlocks()
-			}
-		}
-	}()
+	bm.Run(dt.config.Refresh, dt.MakeBlocks)
 
 	return dt
 }
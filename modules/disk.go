@@ -0,0 +1,217 @@
+package modules
+
+import (
+	"fmt"
+	"path"
+
+	"github.com/dustin/go-humanize"
+	"github.com/shirou/gopsutil/disk"
+	log "github.com/sirupsen/logrus"
+	"github.com/travishegner/goi3status/types"
+)
+
+func init() {
+	addModMap("Disk", NewDisk)
+}
+
+type diskConfig struct {
+	*types.BaseModuleConfig
+	Mount     string
+	Attribute string
+	Include   []string
+	Exclude   []string
+	format    *textFormat
+}
+
+// diskData is the data available to Disk's format templates
+type diskData struct {
+	Mountpoint        string
+	Device            string
+	Fstype            string
+	Total             uint64
+	Free              uint64
+	Used              uint64
+	UsedPercent       float64
+	InodesTotal       uint64
+	InodesUsed        uint64
+	InodesFree        uint64
+	InodesUsedPercent float64
+}
+
+// Disk is a module representing the usage of mounted filesystems
+type Disk struct {
+	*types.BaseModule
+	config *diskConfig
+}
+
+func newDiskConfig(mc types.ModuleConfig) *diskConfig {
+	bmc := types.NewBaseModuleConfig(mc)
+
+	mount, ok := mc["mount"].(string)
+	if !ok {
+		mount = "/"
+	}
+
+	attr, ok := mc["attribute"].(string)
+	if !ok {
+		attr = "used_percent"
+	}
+
+	include := stringList(mc["include_fstypes"])
+	exclude := stringList(mc["exclude_fstypes"])
+	if _, ok := mc["exclude_fstypes"]; !ok {
+		exclude = []string{"tmpfs", "devtmpfs", "overlay", "squashfs"}
+	}
+
+	return &diskConfig{
+		BaseModuleConfig: bmc,
+		Mount:            mount,
+		Attribute:        attr,
+		Include:          include,
+		Exclude:          exclude,
+		format:           newTextFormat(mc),
+	}
+}
+
+// NewDisk returns the Disk module
+func NewDisk(mc types.ModuleConfig) types.Module {
+	config := newDiskConfig(mc)
+	bm := types.NewBaseModule()
+	d := &Disk{
+		BaseModule: bm,
+		config:     config,
+	}
+
+	bm.Run(d.config.Refresh, d.MakeBlocks)
+
+	return d
+}
+
+// MakeBlocks returns the Block array for this module
+func (d *Disk) MakeBlocks() []*types.Block {
+	b := make([]*types.Block, 0)
+	if d.config.Label != "" {
+		block := types.NewBlock(d.config.BlockSeparatorWidth)
+		block.FullText = d.config.Label
+		b = append(b, block)
+	}
+
+	parts, err := d.partitions()
+	if err != nil {
+		log.Warningf("failed to get disk partitions: %v", err.Error())
+		return b
+	}
+
+	blocks := make([]*types.Block, 0)
+	for _, p := range parts {
+		u, err := disk.Usage(p.Mountpoint)
+		if err != nil {
+			log.Warningf("failed to get disk usage of %v: %v", p.Mountpoint, err.Error())
+			continue
+		}
+
+		block := types.NewBlock(d.config.BlockSeparatorWidth)
+		pct := u.UsedPercent
+		switch d.config.Attribute {
+		case "used":
+			block.FullText = humanize.IBytes(u.Used)
+		case "free":
+			block.FullText = humanize.IBytes(u.Free)
+		case "total":
+			block.FullText = humanize.IBytes(u.Total)
+		case "used_percent":
+			block.FullText = fmt.Sprintf("%v%%", int(u.UsedPercent))
+		case "inodes_used":
+			block.FullText = humanize.Comma(int64(u.InodesUsed))
+			pct = u.InodesUsedPercent
+		case "inodes_free":
+			block.FullText = humanize.Comma(int64(u.InodesFree))
+			pct = u.InodesUsedPercent
+		case "inodes_used_percent":
+			block.FullText = fmt.Sprintf("%v%%", int(u.InodesUsedPercent))
+			pct = u.InodesUsedPercent
+		}
+		// with several mounts, say which is which
+		if d.config.Mount == "all" {
+			block.FullText = p.Mountpoint + " " + block.FullText
+		}
+		d.config.Thresholds.Apply(block, p.Mountpoint, pct/100)
+		d.config.format.Apply(block, &diskData{
+			Mountpoint:        p.Mountpoint,
+			Device:            p.Device,
+			Fstype:            p.Fstype,
+			Total:             u.Total,
+			Free:              u.Free,
+			Used:              u.Used,
+			UsedPercent:       u.UsedPercent,
+			InodesTotal:       u.InodesTotal,
+			InodesUsed:        u.InodesUsed,
+			InodesFree:        u.InodesFree,
+			InodesUsedPercent: u.InodesUsedPercent,
+		})
+		blocks = append(blocks, block)
+	}
+
+	if len(blocks) > 0 {
+		block := blocks[len(blocks)-1]
+		block.SeparatorBlockWidth = d.config.FinalSeparatorWidth
+		if d.config.FinalSeparator {
+			block.AddSeparator()
+		}
+	}
+
+	return append(b, blocks...)
+}
+
+// partitions returns the configured mount, or every real mount whose
+// filesystem type passes the include and exclude globs
+func (d *Disk) partitions() ([]disk.PartitionStat, error) {
+	all, err := disk.Partitions(false)
+	if err != nil {
+		return nil, err
+	}
+
+	parts := make([]disk.PartitionStat, 0)
+	for _, p := range all {
+		if d.config.Mount != "all" {
+			if p.Mountpoint == d.config.Mount {
+				return []disk.PartitionStat{p}, nil
+			}
+			continue
+		}
+		if len(d.config.Include) > 0 && !matchAny(d.config.Include, p.Fstype) {
+			continue
+		}
+		if matchAny(d.config.Exclude, p.Fstype) {
+			continue
+		}
+		parts = append(parts, p)
+	}
+
+	// the mount may be hidden from the partition list (e.g. in a container)
+	if d.config.Mount != "all" {
+		return []disk.PartitionStat{{Mountpoint: d.config.Mount}}, nil
+	}
+
+	return parts, nil
+}
+
+// GetUpdateChan returns the channel down which new block arrays are sent
+func (d *Disk) GetUpdateChan() chan []*types.Block {
+	return d.Update
+}
+
+// Stop stops this module from polling and sending updated Block arrays
+func (d *Disk) Stop() {
+	close(d.Done)
+}
+
+// matchAny returns true if name matches any of the glob patterns
+func matchAny(patterns []string, name string) bool {
+	for _, p := range patterns {
+		if ok, _ := path.Match(p, name); ok {
+			return true
+		}
+	}
+	return false
+}
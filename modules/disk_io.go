@@ -0,0 +1,281 @@
+package modules
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dustin/go-humanize"
+	log "github.com/sirupsen/logrus"
+	"github.com/travishegner/goi3status/types"
+)
+
+func init() {
+	addModMap("DiskIO", NewDiskIO)
+}
+
+// the kernel always counts sectors as 512 bytes in /proc/diskstats
+const diskSectorSize = 512
+
+type diskIOConfig struct {
+	*types.BaseModuleConfig
+	Devices    []string
+	Exclude    []string
+	Partitions bool
+	Aggregate  bool
+	Attribute  string
+	format     *textFormat
+}
+
+// diskIOSample holds the cumulative counters of a device from /proc/diskstats
+type diskIOSample struct {
+	reads      uint64
+	writes     uint64
+	readBytes  uint64
+	writeBytes uint64
+	busyMs     uint64
+}
+
+// resetSince returns true if any counter is lower than in prev, as happens
+// when a device is removed and plugged back in
+func (s *diskIOSample) resetSince(prev *diskIOSample) bool {
+	return s.reads < prev.reads || s.writes < prev.writes ||
+		s.readBytes < prev.readBytes || s.writeBytes < prev.writeBytes ||
+		s.busyMs < prev.busyMs
+}
+
+// diskIOData is the data available to DiskIO's format templates
+type diskIOData struct {
+	Device string
+	// ReadRate and WriteRate are in bytes per second
+	ReadRate  float64
+	WriteRate float64
+	ReadIOPS  float64
+	WriteIOPS float64
+	// Util is the percentage of time the device was busy
+	Util float64
+}
+
+// DiskIO is a module representing the throughput of block devices
+type DiskIO struct {
+	*types.BaseModule
+	config       *diskIOConfig
+	last         map[string]*diskIOSample
+	lastReadTime time.Time
+}
+
+func newDiskIOConfig(mc types.ModuleConfig) *diskIOConfig {
+	bmc := types.NewBaseModuleConfig(mc)
+
+	devices := stringList(mc["devices"])
+	if len(devices) == 0 {
+		devices = []string{"*"}
+	}
+
+	exclude := stringList(mc["exclude"])
+	if _, ok := mc["exclude"]; !ok {
+		exclude = []string{"loop*", "ram*", "zram*"}
+	}
+
+	parts, ok := mc["partitions"].(bool)
+	if !ok {
+		parts = false
+	}
+
+	agg, ok := mc["aggregate"].(bool)
+	if !ok {
+		agg = false
+	}
+
+	attr, ok := mc["attribute"].(string)
+	if !ok {
+		attr = "both"
+	}
+
+	return &diskIOConfig{
+		BaseModuleConfig: bmc,
+		Devices:          devices,
+		Exclude:          exclude,
+		Partitions:       parts,
+		Aggregate:        agg,
+		Attribute:        attr,
+		format:           newTextFormat(mc),
+	}
+}
+
+// NewDiskIO returns the DiskIO module
+func NewDiskIO(mc types.ModuleConfig) types.Module {
+	config := newDiskIOConfig(mc)
+	bm := types.NewBaseModule()
+	d := &DiskIO{
+		BaseModule: bm,
+		config:     config,
+		last:       make(map[string]*diskIOSample),
+	}
+
+	bm.Run(d.config.Refresh, d.MakeBlocks)
+
+	return d
+}
+
+// MakeBlocks returns the Block array for this module
+func (d *DiskIO) MakeBlocks() []*types.Block {
+	b := make([]*types.Block, 0)
+	if d.config.Label != "" {
+		block := types.NewBlock(d.config.BlockSeparatorWidth)
+		block.FullText = d.config.Label
+		b = append(b, block)
+	}
+
+	samples, names, err := readDiskStats("/proc/diskstats")
+	if err != nil {
+		log.Errorf("failed to get disk stats: %v", err.Error())
+		return b
+	}
+
+	now := time.Now()
+	elapsed := now.Sub(d.lastReadTime).Seconds()
+	first := d.lastReadTime.IsZero()
+	d.lastReadTime = now
+
+	agg := &diskIOData{Device: "all"}
+	count := 0
+	blocks := make([]*types.Block, 0)
+	for _, name := range names {
+		if !d.wantDevice(name) {
+			continue
+		}
+		cur := samples[name]
+		prev, ok := d.last[name]
+		d.last[name] = cur
+		// a reset sample only seeds the next one
+		if first || !ok || cur.resetSince(prev) {
+			continue
+		}
+
+		data := &diskIOData{
+			Device:    name,
+			ReadRate:  float64(cur.readBytes-prev.readBytes) / elapsed,
+			WriteRate: float64(cur.writeBytes-prev.writeBytes) / elapsed,
+			ReadIOPS:  float64(cur.reads-prev.reads) / elapsed,
+			WriteIOPS: float64(cur.writes-prev.writes) / elapsed,
+			Util:      float64(cur.busyMs-prev.busyMs) / (elapsed * 10),
+		}
+
+		if d.config.Aggregate {
+			agg.ReadRate += data.ReadRate
+			agg.WriteRate += data.WriteRate
+			agg.ReadIOPS += data.ReadIOPS
+			agg.WriteIOPS += data.WriteIOPS
+			// the busiest device is the bottleneck
+			if data.Util > agg.Util {
+				agg.Util = data.Util
+			}
+			count++
+			continue
+		}
+
+		blocks = append(blocks, d.makeBlock(data, true))
+	}
+
+	if d.config.Aggregate && count > 0 {
+		blocks = append(blocks, d.makeBlock(agg, false))
+	}
+
+	if len(blocks) > 0 {
+		block := blocks[len(blocks)-1]
+		block.SeparatorBlockWidth = d.config.FinalSeparatorWidth
+		if d.config.FinalSeparator {
+			block.AddSeparator()
+		}
+	}
+
+	return append(b, blocks...)
+}
+
+func (d *DiskIO) makeBlock(data *diskIOData, named bool) *types.Block {
+	block := types.NewBlock(d.config.BlockSeparatorWidth)
+	read := humanize.IBytes(uint64(data.ReadRate)) + "/s↓"
+	write := humanize.IBytes(uint64(data.WriteRate)) + "/s↑"
+	switch d.config.Attribute {
+	case "read":
+		block.FullText = read
+	case "write":
+		block.FullText = write
+	default:
+		block.FullText = read + " " + write
+	}
+	if named {
+		block.FullText = data.Device + " " + block.FullText
+	}
+
+	d.config.Thresholds.Apply(block, data.Device, data.Util/100)
+	d.config.format.Apply(block, data)
+	return block
+}
+
+// wantDevice returns true if the device passes the configured filters
+func (d *DiskIO) wantDevice(name string) bool {
+	if !matchAny(d.config.Devices, name) || matchAny(d.config.Exclude, name) {
+		return false
+	}
+	if d.config.Partitions {
+		return true
+	}
+	// only whole disks appear directly under /sys/block
+	_, err := os.Stat("/sys/block/" + strings.ReplaceAll(name, "/", "!"))
+	return err == nil
+}
+
+// GetUpdateChan returns the channel down which new block arrays are sent
+func (d *DiskIO) GetUpdateChan() chan []*types.Block {
+	return d.Update
+}
+
+// Stop stops this module from polling and sending updated Block arrays
+func (d *DiskIO) Stop() {
+	close(d.Done)
+}
+
+// readDiskStats parses a file in the format of /proc/diskstats, returning
+// the samples by device name along with the names in file order
+func readDiskStats(path string) (map[string]*diskIOSample, []string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+
+	samples := make(map[string]*diskIOSample)
+	names := make([]string, 0)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 14 {
+			continue
+		}
+
+		vals := make([]uint64, len(fields))
+		for i := 3; i < len(fields); i++ {
+			vals[i], err = strconv.ParseUint(fields[i], 10, 64)
+			if err != nil {
+				return nil, nil, fmt.Errorf("bad value in %v for %v: %v", path, fields[2], err)
+			}
+		}
+
+		name := fields[2]
+		samples[name] = &diskIOSample{
+			reads:      vals[3],
+			readBytes:  vals[5] * diskSectorSize,
+			writes:     vals[7],
+			writeBytes: vals[9] * diskSectorSize,
+			busyMs:     vals[12],
+		}
+		names = append(names, name)
+	}
+
+	return samples, names, scanner.Err()
+}
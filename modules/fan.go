@@ -0,0 +1,212 @@
+package modules
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/travishegner/goi3status/types"
+)
+
+func init() {
+	addModMap("Fan", NewFan)
+}
+
+// fanLimits is the speed range of a fan, in revolutions per minute
+type fanLimits struct {
+	min float64
+	max float64
+}
+
+type fanConfig struct {
+	*types.BaseModuleConfig
+	chip      *regexp.Regexp
+	sensor    *regexp.Regexp
+	attribute string
+	limits    fanLimits
+	perFan    map[string]fanLimits
+	stallTemp float64
+	format    *textFormat
+}
+
+// fanData is the data available to Fan's format templates
+type fanData struct {
+	Chip    string
+	Label   string
+	RPM     float64
+	Min     float64
+	Max     float64
+	Percent float64
+	Stalled bool
+}
+
+// Fan is a module representing the speed of hwmon fans
+type Fan struct {
+	*types.BaseModule
+	config *fanConfig
+}
+
+func newFanConfig(mc types.ModuleConfig) *fanConfig {
+	bmc := types.NewBaseModuleConfig(mc)
+
+	attr, ok := mc["attribute"].(string)
+	if !ok {
+		attr = "rpm"
+	}
+
+	minRPM, ok := mc["min_rpm"].(int)
+	if !ok {
+		minRPM = 0
+	}
+
+	maxRPM, ok := mc["max_rpm"].(int)
+	if !ok {
+		maxRPM = 3000
+	}
+
+	// per fan limits, keyed by the fan's label
+	perFan := make(map[string]fanLimits)
+	fans, _ := mc["fans"].(map[interface{}]interface{})
+	for k, v := range fans {
+		label, _ := k.(string)
+		fc, _ := v.(map[interface{}]interface{})
+		l := fanLimits{min: float64(minRPM), max: float64(maxRPM)}
+		if min, ok := fc["min_rpm"].(int); ok {
+			l.min = float64(min)
+		}
+		if max, ok := fc["max_rpm"].(int); ok {
+			l.max = float64(max)
+		}
+		perFan[label] = l
+	}
+
+	stallTemp, ok := mc["stall_temp"].(int)
+	if !ok {
+		stallTemp = 70
+	}
+
+	return &fanConfig{
+		BaseModuleConfig: bmc,
+		chip:             configRegexp(mc, "chip"),
+		sensor:           configRegexp(mc, "sensor"),
+		attribute:        attr,
+		limits:           fanLimits{min: float64(minRPM), max: float64(maxRPM)},
+		perFan:           perFan,
+		stallTemp:        float64(stallTemp),
+		format:           newTextFormat(mc),
+	}
+}
+
+// NewFan returns the Fan module
+func NewFan(mc types.ModuleConfig) types.Module {
+	config := newFanConfig(mc)
+	bm := types.NewBaseModule()
+	f := &Fan{
+		BaseModule: bm,
+		config:     config,
+	}
+
+	bm.Run(f.config.Refresh, f.MakeBlocks)
+
+	return f
+}
+
+// MakeBlocks returns the Block array for this module
+func (f *Fan) MakeBlocks() []*types.Block {
+	b := make([]*types.Block, 0)
+	if f.config.Label != "" {
+		block := types.NewBlock(f.config.BlockSeparatorWidth)
+		block.FullText = f.config.Label
+		b = append(b, block)
+	}
+
+	fans := filterFans(readFanSensors(sysfsRoot), f.config.chip, f.config.sensor)
+	if len(fans) == 0 {
+		log.Warnf("no fans matched")
+		return b
+	}
+
+	// a stopped fan only matters if something is getting hot
+	hot := false
+	if temps := readTempSensors(sysfsRoot); len(temps) > 0 {
+		hot = maxSensor(temps).Temp >= f.config.stallTemp
+	}
+
+	for i, fs := range fans {
+		l := f.limitsFor(fs)
+		pct := 0.0
+		if l.max > l.min {
+			pct = (fs.RPM - l.min) / (l.max - l.min) * 100
+		}
+		pct = math.Max(0, math.Min(100, pct))
+
+		block := types.NewBlock(f.config.BlockSeparatorWidth)
+		switch f.config.attribute {
+		case "percent":
+			block.FullText = fmt.Sprintf("%v%%", int(pct))
+		default:
+			block.FullText = fmt.Sprintf("%vrpm", int(fs.RPM))
+		}
+
+		stalled := fs.RPM == 0 && hot
+		if stalled {
+			// a stalled fan is at the bad end of the scale, whichever way
+			// round it is
+			worst := 1.0
+			if f.config.Thresholds.Invert {
+				worst = 0
+			}
+			f.config.Thresholds.Apply(block, fs.Chip+"/"+fs.Label, worst)
+			block.Urgent = true
+		} else {
+			f.config.Thresholds.Apply(block, fs.Chip+"/"+fs.Label, pct/100)
+		}
+
+		f.config.format.Apply(block, &fanData{
+			Chip:    fs.Chip,
+			Label:   fs.Label,
+			RPM:     fs.RPM,
+			Min:     l.min,
+			Max:     l.max,
+			Percent: pct,
+			Stalled: stalled,
+		})
+
+		if i == len(fans)-1 {
+			block.SeparatorBlockWidth = f.config.FinalSeparatorWidth
+			if f.config.FinalSeparator {
+				block.AddSeparator()
+			}
+		}
+		b = append(b, block)
+	}
+
+	return b
+}
+
+// limitsFor returns the configured limits for the fan, preferring the
+// fan's own min and max over the module wide defaults
+func (f *Fan) limitsFor(fs *fanSensor) fanLimits {
+	if l, ok := f.config.perFan[fs.Label]; ok {
+		return l
+	}
+	l := f.config.limits
+	if fs.Min > 0 {
+		l.min = fs.Min
+	}
+	if fs.Max > 0 {
+		l.max = fs.Max
+	}
+	return l
+}
+
+// GetUpdateChan returns the channel down which new block arrays are sent
+func (f *Fan) GetUpdateChan() chan []*types.Block {
+	return f.Update
+}
+
+// Stop stops this module from polling and sending updated Block arrays
+func (f *Fan) Stop() {
+	close(f.Done)
+}
@@ -0,0 +1,106 @@
+package modules
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/travishegner/goi3status/types"
+)
+
+// testFanTree has a labelled fan with its own max, an unlabelled fan, and
+// a stopped fan, alongside a temperature of 75C
+var testFanTree = map[string]string{
+	"class/hwmon/hwmon0/name":              "nct6775",
+	"class/hwmon/hwmon0/fan1_input":        "1200",
+	"class/hwmon/hwmon0/fan1_label":        "CPU Fan",
+	"class/hwmon/hwmon0/fan1_max":          "2400",
+	"class/hwmon/hwmon0/fan2_input":        "900",
+	"class/hwmon/hwmon0/fan3_input":        "0",
+	"class/hwmon/hwmon0/fan3_min":          "300",
+	"class/hwmon/hwmon0/temp1_input":       "75000",
+	"class/hwmon/hwmon1/device/name":       "it87",
+	"class/hwmon/hwmon1/device/fan1_input": "600",
+}
+
+func TestReadFanSensors(t *testing.T) {
+	root := writeSysfs(t, testFanTree)
+
+	got := readFanSensors(root)
+	want := []*fanSensor{
+		{Chip: "nct6775", Label: "CPU Fan", RPM: 1200, Max: 2400},
+		{Chip: "nct6775", Label: "fan2", RPM: 900},
+		{Chip: "nct6775", Label: "fan3", RPM: 0, Min: 300},
+		{Chip: "it87", Label: "fan1", RPM: 600},
+	}
+	if !reflect.DeepEqual(got, want) {
+		for _, f := range got {
+			t.Errorf("readFanSensors() got %+v", *f)
+		}
+	}
+}
+
+func TestFanLimits(t *testing.T) {
+	f := &Fan{config: newFanConfig(types.ModuleConfig{
+		"min_rpm": 100,
+		"max_rpm": 2000,
+		"fans": map[interface{}]interface{}{
+			"fan2": map[interface{}]interface{}{"max_rpm": 1800},
+		},
+	})}
+
+	tests := []struct {
+		name string
+		fan  *fanSensor
+		want fanLimits
+	}{
+		{"defaults", &fanSensor{Label: "fan1"}, fanLimits{min: 100, max: 2000}},
+		{"fan's own max", &fanSensor{Label: "fan1", Max: 2400}, fanLimits{min: 100, max: 2400}},
+		{"fan's own min", &fanSensor{Label: "fan1", Min: 600}, fanLimits{min: 600, max: 2000}},
+		{"configured fan", &fanSensor{Label: "fan2", Max: 2400}, fanLimits{min: 100, max: 1800}},
+	}
+	for _, tt := range tests {
+		if got := f.limitsFor(tt.fan); got != tt.want {
+			t.Errorf("%v: limitsFor() = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestFanBlocks(t *testing.T) {
+	writeSysfs(t, testFanTree)
+
+	tests := []struct {
+		name      string
+		stallTemp int
+		texts     []string
+		urgent    []bool
+	}{
+		// CPU Fan uses fan1_max, fan2 its own max_rpm, and it87's fan1 the
+		// module's max_rpm
+		{"cool", 80, []string{"50%", "60%", "0%", "20%"}, []bool{false, false, false, false}},
+		{"stalled", 70, []string{"50%", "60%", "0%", "20%"}, []bool{false, false, true, false}},
+	}
+	for _, tt := range tests {
+		f := &Fan{
+			BaseModule: types.NewBaseModule(),
+			config: newFanConfig(types.ModuleConfig{
+				"attribute":  "percent",
+				"max_rpm":    3000,
+				"stall_temp": tt.stallTemp,
+				"fans": map[interface{}]interface{}{
+					"fan2": map[interface{}]interface{}{"max_rpm": 1500},
+				},
+			}),
+		}
+
+		blocks := f.MakeBlocks()
+		if len(blocks) != len(tt.texts) {
+			t.Fatalf("%v: got %v blocks, want %v", tt.name, len(blocks), len(tt.texts))
+		}
+		for i, b := range blocks {
+			if b.FullText != tt.texts[i] || b.Urgent != tt.urgent[i] {
+				t.Errorf("%v: block %v = %q urgent %v, want %q urgent %v",
+					tt.name, i, b.FullText, b.Urgent, tt.texts[i], tt.urgent[i])
+			}
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package modules
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"text/template"
+	"unicode/utf8"
+
+	"github.com/dustin/go-humanize"
+	log "github.com/sirupsen/logrus"
+	"github.com/travishegner/goi3status/types"
+)
+
+// formatFuncs are available to every format and short_format template
+var formatFuncs = template.FuncMap{
+	// bytes humanizes a byte count, e.g. 1.2 GiB
+	"bytes": func(v interface{}) string {
+		return humanize.IBytes(uint64(toFloat(v)))
+	},
+	// bits humanizes a bit rate, e.g. 12 Mb
+	"bits": func(v interface{}) string {
+		return humanize.SIWithDigits(toFloat(v), 1, "b")
+	},
+	// percent formats a number from 0 to 100 as a whole percentage, e.g. 42%
+	"percent": func(v interface{}) string {
+		return fmt.Sprintf("%v%%", int(toFloat(v)))
+	},
+	// fixed formats a number with the given count of decimal places
+	"fixed": func(places int, v interface{}) string {
+		return fmt.Sprintf("%.*f", places, toFloat(v))
+	},
+	// pad left pads s with spaces to width characters
+	"pad": func(width int, s interface{}) string {
+		str := fmt.Sprint(s)
+		return strings.Repeat(" ", max(0, width-utf8.RuneCountInString(str))) + str
+	},
+	// rpad right pads s with spaces to width characters
+	"rpad": func(width int, s interface{}) string {
+		str := fmt.Sprint(s)
+		return str + strings.Repeat(" ", max(0, width-utf8.RuneCountInString(str)))
+	},
+}
+
+// textFormat holds the optional format and short_format templates of a module
+type textFormat struct {
+	full  *template.Template
+	short *template.Template
+}
+
+func newTextFormat(mc types.ModuleConfig) *textFormat {
+	return &textFormat{
+		full:  parseFormat(mc, "format"),
+		short: parseFormat(mc, "short_format"),
+	}
+}
+
+func parseFormat(mc types.ModuleConfig, key string) *template.Template {
+	text, ok := mc[key].(string)
+	if !ok || text == "" {
+		return nil
+	}
+
+	t, err := template.New(key).Funcs(formatFuncs).Parse(text)
+	if err != nil {
+		log.Errorf("error parsing %v template: %v", key, err)
+		return nil
+	}
+	return t
+}
+
+// IsSet returns true if a format template was configured, in which case it
+// replaces the module's usual text
+func (f *textFormat) IsSet() bool {
+	return f.full != nil
+}
+
+// Apply executes the configured templates against data, setting the
+// FullText and ShortText of block
+func (f *textFormat) Apply(block *types.Block, data interface{}) {
+	if f.full != nil {
+		block.FullText = execFormat(f.full, data)
+	}
+	if f.short != nil {
+		block.ShortText = execFormat(f.short, data)
+	}
+}
+
+func execFormat(t *template.Template, data interface{}) string {
+	var sb strings.Builder
+	err := t.Execute(&sb, data)
+	if err != nil {
+		log.Errorf("error executing %v template: %v", t.Name(), err)
+		return err.Error()
+	}
+	return sb.String()
+}
+
+// toFloat returns any kind of number as a float64, or 0 if v isn't one
+func toFloat(v interface{}) float64 {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return float64(rv.Uint())
+	case reflect.Float32, reflect.Float64:
+		return rv.Float()
+	}
+	return 0
+}
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,48 @@
+package modules
+
+import (
+	"testing"
+
+	"github.com/travishegner/goi3status/types"
+)
+
+func TestToFloat(t *testing.T) {
+	tests := []struct {
+		v    interface{}
+		want float64
+	}{
+		{int(-3), -3},
+		{int32(7), 7},
+		{int64(1 << 40), 1 << 40},
+		{uint8(200), 200},
+		{uint32(12), 12},
+		{uint64(1 << 50), 1 << 50},
+		{float32(1.5), 1.5},
+		{2.25, 2.25},
+		{"12", 0},
+		{nil, 0},
+	}
+	for _, tt := range tests {
+		if got := toFloat(tt.v); got != tt.want {
+			t.Errorf("toFloat(%#v) = %v, want %v", tt.v, got, tt.want)
+		}
+	}
+}
+
+func TestTextFormat(t *testing.T) {
+	f := newTextFormat(types.ModuleConfig{
+		"format":       "{{bytes .Used}} {{percent .Percent}}",
+		"short_format": "{{fixed 1 .Load}}",
+	})
+	data := struct {
+		Used    uint64
+		Percent float32
+		Load    int32
+	}{Used: 3 << 30, Percent: 42.7, Load: 2}
+
+	block := types.NewBlock(0)
+	f.Apply(block, data)
+	if block.FullText != "3.0 GiB 42%" || block.ShortText != "2.0" {
+		t.Errorf("Apply() = %q, %q", block.FullText, block.ShortText)
+	}
+}
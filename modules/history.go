@@ -0,0 +1,153 @@
+package modules
+
+import (
+	"math"
+
+	"github.com/travishegner/goi3status/types"
+)
+
+// sparkChars are the glyphs of a sparkline, from lowest to highest
+var sparkChars = []string{"▁", "▂", "▃", "▄", "▅", "▆", "▇", "█"}
+
+// sparkChar returns the glyph for a value between 0 and 1
+func sparkChar(ratio float64) string {
+	if math.IsNaN(ratio) {
+		ratio = 0
+	}
+	i := int(ratio * float64(len(sparkChars)-1))
+	if i < 0 {
+		i = 0
+	}
+	if i > len(sparkChars)-1 {
+		i = len(sparkChars) - 1
+	}
+	return sparkChars[i]
+}
+
+// history is a fixed size ring buffer of samples
+type history struct {
+	samples []float64
+	next    int
+	full    bool
+}
+
+func newHistory(size int) *history {
+	return &history{samples: make([]float64, size)}
+}
+
+// Push adds a sample, overwriting the oldest once the buffer is full
+func (h *history) Push(v float64) {
+	h.samples[h.next] = v
+	h.next = (h.next + 1) % len(h.samples)
+	if h.next == 0 {
+		h.full = true
+	}
+}
+
+// Values returns the samples, oldest first
+func (h *history) Values() []float64 {
+	if !h.full {
+		return append([]float64{}, h.samples[:h.next]...)
+	}
+	return append(append([]float64{}, h.samples[h.next:]...), h.samples[:h.next]...)
+}
+
+// sparkline renders the recent history of a module's values. It is
+// configured by the history, history_scale, history_max and history_color
+// keys of a module config.
+type sparkline struct {
+	size int
+	// scale is "fixed" to draw against a maximum, or "auto" to draw against
+	// the largest sample in the window
+	scale string
+	max   float64
+	// color is "last" to color the whole line by the latest sample, or
+	// "samples" to color each sample on its own
+	color   string
+	history map[string]*history
+}
+
+func newSparkline(mc types.ModuleConfig) *sparkline {
+	size, ok := mc["history"].(int)
+	if !ok || size < 0 {
+		size = 0
+	}
+
+	scale, ok := mc["history_scale"].(string)
+	if !ok {
+		scale = "fixed"
+	}
+
+	color, ok := mc["history_color"].(string)
+	if !ok {
+		color = "last"
+	}
+
+	return &sparkline{
+		size:    size,
+		scale:   scale,
+		max:     toFloat(mc["history_max"]),
+		color:   color,
+		history: make(map[string]*history),
+	}
+}
+
+// IsSet reports whether the module should draw a sparkline
+func (s *sparkline) IsSet() bool {
+	return s.size > 0
+}
+
+// Blocks records value in key's history and returns the blocks drawing it.
+// limit is the value which the thresholds treat as 100%, and the top of a
+// fixed scale unless history_max is set.
+func (s *sparkline) Blocks(key string, value, limit float64, sepWidth int, t *types.Thresholds) []*types.Block {
+	h, ok := s.history[key]
+	if !ok {
+		h = newHistory(s.size)
+		s.history[key] = h
+	}
+	h.Push(value)
+	values := h.Values()
+
+	top := limit
+	if s.max > 0 {
+		top = s.max
+	}
+	if s.scale == "auto" {
+		top = 0
+		for _, v := range values {
+			top = math.Max(top, v)
+		}
+	}
+	ratio := func(v float64) float64 {
+		if top <= 0 {
+			return 0
+		}
+		return v / top
+	}
+
+	if s.color != "samples" {
+		block := types.NewBlock(sepWidth)
+		for _, v := range values {
+			block.FullText += sparkChar(ratio(v))
+		}
+		t.Apply(block, key, value/limit)
+		return []*types.Block{block}
+	}
+
+	blocks := make([]*types.Block, 0, len(values))
+	for i, v := range values {
+		block := types.NewBlock(0)
+		block.FullText = sparkChar(ratio(v))
+		if i == len(values)-1 {
+			block.SeparatorBlockWidth = sepWidth
+			t.Apply(block, key, v/limit)
+		} else {
+			// past samples have their own key, so they don't upset the
+			// hysteresis of the current value
+			block.Color, _ = t.Get(key+"/history", v/limit)
+		}
+		blocks = append(blocks, block)
+	}
+	return blocks
+}
@@ -4,6 +4,8 @@ import (
 	"bufio"
 	"fmt"
 	"os"
+	"strconv"
+	"strings"
 
 	"github.com/travishegner/goi3status/types"
 )
@@ -24,28 +26,6 @@ func GetModule(name string, mc types.ModuleConfig) (types.Module, error) {
 	return cm(mc), nil
 }
 
-// GetColor returns a color between green and red where 0 = green and 100 = red
-func GetColor(n float64) string {
-	if n > 1 {
-		n = 1
-	}
-	// #00FF00
-	r := int(255 * (n * 2))
-	g := 255
-	b := 0
-
-	if r >= 255 {
-		r = 255
-		g = int(255 * ((1 - n) * 2))
-	}
-
-	if g > 255 {
-		g = 255
-	}
-
-	return fmt.Sprintf("#%0.2x%0.2x%0.2x", r, g, b)
-}
-
 func readLine(path string) string {
 	inFile, _ := os.Open(path)
 	defer inFile.Close()
@@ -55,3 +35,25 @@ func readLine(path string) string {
 	scanner.Scan()
 	return scanner.Text()
 }
+
+// stringList returns a config value which may be a single string or a list
+// of strings as a slice
+func stringList(v interface{}) []string {
+	l := make([]string, 0)
+	switch t := v.(type) {
+	case string:
+		l = append(l, t)
+	case []interface{}:
+		for _, i := range t {
+			if s, ok := i.(string); ok {
+				l = append(l, s)
+			}
+		}
+	}
+	return l
+}
+
+// readInt returns the integer on the first line of the file at path
+func readInt(path string) (int64, error) {
+	return strconv.ParseInt(strings.TrimSpace(readLine(path)), 10, 64)
+}
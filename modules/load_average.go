@@ -2,10 +2,9 @@ package modules
 
 import (
 	"fmt"
-	"time"
 
-	"github.com/shirou/gopsutil/cpu"
 	"github.com/shirou/gopsutil/load"
+
 	log "github.com/sirupsen/logrus"
 
 	"github.com/travishegner/goi3status/types"
@@ -17,12 +16,23 @@ func init() {
 
 type loadAverageConfig struct {
 	*types.BaseModuleConfig
+	format  *textFormat
+	history *sparkline
+}
+
+// loadAverageData is the data available to LoadAverage's format templates
+type loadAverageData struct {
+	Load1  float64
+	Load5  float64
+	Load15 float64
+	Cores  int
 }
 
 // LoadAverage is a module representing the machines load average
 type LoadAverage struct {
 	*types.BaseModule
 	config *loadAverageConfig
+	load   *sampleSub
 }
 
 func newLoadAverageConfig(mc types.ModuleConfig) *loadAverageConfig {
@@ -30,6 +40,8 @@ func newLoadAverageConfig(mc types.ModuleConfig) *loadAverageConfig {
 
 	return &loadAverageConfig{
 		BaseModuleConfig: bmc,
+		format:           newTextFormat(mc),
+		history:          newSparkline(mc),
 	}
 }
 
@@ -42,19 +54,8 @@ func NewLoadAverage(mc types.ModuleConfig) types.Module {
 		config:     config,
 	}
 
-	bm.Update <- la.MakeBlocks()
-	ticker := time.NewTicker(la.config.Refresh)
-
-	go func() {
-		for {
-			select {
-			case <-bm.Done:
-				return
-			case <-ticker.C:
-				bm.Update <- la.MakeBlocks()
-			}
-		}
-	}()
+	la.load = subscribe("load", config.Refresh, bm.Refresh)
+	bm.Run(la.config.Refresh, la.MakeBlocks)
 
 	return la
 }
@@ -68,27 +69,53 @@ func (la *LoadAverage) MakeBlocks() []*types.Block {
 		b = append(b, block)
 	}
 
-	c, err := cpu.Counts(false)
+	c, err := sampleCPUCounts()
 	if err != nil {
 		log.Error(err)
 		c = 1
 	}
 	cores := float64(c)
 
-	avg, err := load.Avg()
-	if err != nil {
-		log.Error(err)
+	snap := la.load.Latest()
+	if snap.err != nil {
+		log.Error(snap.err)
 		return b
 	}
+	avg := snap.value.(*load.AvgStat)
+
+	// the history is of the 1 minute average, scaled to the number of cores
+	if la.config.history.IsSet() {
+		hb := la.config.history.Blocks("load1", avg.Load1, cores, la.config.FinalSeparatorWidth, la.config.Thresholds)
+		if la.config.FinalSeparator {
+			hb[len(hb)-1].AddSeparator()
+		}
+		return append(b, hb...)
+	}
+
+	// a format template renders all three averages in one block
+	if la.config.format.IsSet() {
+		block := types.NewBlock(la.config.FinalSeparatorWidth)
+		if la.config.FinalSeparator {
+			block.AddSeparator()
+		}
+		la.config.Thresholds.Apply(block, "load1", avg.Load1/cores)
+		la.config.format.Apply(block, &loadAverageData{
+			Load1:  avg.Load1,
+			Load5:  avg.Load5,
+			Load15: avg.Load15,
+			Cores:  c,
+		})
+		return append(b, block)
+	}
 
 	block := types.NewBlock(la.config.BlockSeparatorWidth)
 	block.FullText = fmt.Sprintf("%01.02v", avg.Load1)
-	block.Color = GetColor(avg.Load1 / cores)
+	la.config.Thresholds.Apply(block, "load1", avg.Load1/cores)
 	b = append(b, block)
 
 	block = types.NewBlock(la.config.BlockSeparatorWidth)
 	block.FullText = fmt.Sprintf("%01.02v", avg.Load5)
-	block.Color = GetColor(avg.Load5 / cores)
+	la.config.Thresholds.Apply(block, "load5", avg.Load5/cores)
 	b = append(b, block)
 
 	block = types.NewBlock(la.config.FinalSeparatorWidth)
@@ -96,7 +123,7 @@ func (la *LoadAverage) MakeBlocks() []*types.Block {
 		block.AddSeparator()
 	}
 	block.FullText = fmt.Sprintf("%01.02v", avg.Load15)
-	block.Color = GetColor(avg.Load15 / cores)
+	la.config.Thresholds.Apply(block, "load15", avg.Load15/cores)
 	b = append(b, block)
 
 	return b
@@ -110,4 +137,5 @@ func (la *LoadAverage) GetUpdateChan() chan []*types.Block {
 // Stop stops this module from polling and sending updated Block arrays
 func (la *LoadAverage) Stop() {
 	close(la.Done)
+	la.load.Close()
 }
@@ -3,7 +3,6 @@ package modules
 import (
 	"fmt"
 	"strings"
-	"time"
 
 	"github.com/dustin/go-humanize"
 	"github.com/shirou/gopsutil/mem"
@@ -18,12 +17,32 @@ func init() {
 type memoryConfig struct {
 	*types.BaseModuleConfig
 	Attribute string
+	format    *textFormat
+	history   *sparkline
+}
+
+// memoryStat is a snapshot of either ram or swap usage
+type memoryStat struct {
+	Total       uint64
+	Available   uint64
+	Used        uint64
+	Free        uint64
+	UsedPercent float64
+}
+
+// memoryData is the data available to Memory's format templates
+type memoryData struct {
+	RAM  memoryStat
+	Swap memoryStat
 }
 
 // Memory is a module representing the machines memory
 type Memory struct {
 	*types.BaseModule
 	config *memoryConfig
+	// swap and ram are only subscribed to if they are shown
+	swap *sampleSub
+	ram  *sampleSub
 }
 
 func newMemoryConfig(mc types.ModuleConfig) *memoryConfig {
@@ -37,6 +56,8 @@ func newMemoryConfig(mc types.ModuleConfig) *memoryConfig {
 	return &memoryConfig{
 		BaseModuleConfig: bmc,
 		Attribute:        attr,
+		format:           newTextFormat(mc),
+		history:          newSparkline(mc),
 	}
 }
 
@@ -49,19 +70,16 @@ func NewMemory(mc types.ModuleConfig) types.Module {
 		config:     config,
 	}
 
-	bm.Update <- m.MakeBlocks()
-	ticker := time.NewTicker(m.config.Refresh)
-
-	go func() {
-		for {
-			select {
-			case <-bm.Done:
-				return
-			case <-ticker.C:
-				bm.Update <- m.MakeBlocks()
-			}
-		}
-	}()
+	// a format template may use both, so only read what will be shown
+	kind := strings.Split(config.Attribute, "_")[0]
+	if kind == "swap" || config.format.IsSet() {
+		m.swap = subscribe("swap_mem", config.Refresh, bm.Refresh)
+	}
+	if kind == "ram" || config.format.IsSet() {
+		m.ram = subscribe("virtual_mem", config.Refresh, bm.Refresh)
+	}
+
+	bm.Run(m.config.Refresh, m.MakeBlocks)
 
 	return m
 }
@@ -75,30 +93,65 @@ func (m *Memory) MakeBlocks() []*types.Block {
 		b = append(b, block)
 	}
 
-	var err error
 	block := types.NewBlock(m.config.FinalSeparatorWidth)
 	if m.config.FinalSeparator {
 		block.AddSeparator()
 	}
 
+	// a format template may use both, but the attribute still picks the color
+	kind := strings.Split(m.config.Attribute, "_")[0]
+	data := &memoryData{}
+
 	var swp *mem.SwapMemoryStat
-	if strings.Split(m.config.Attribute, "_")[0] == "swap" {
-		swp, err = mem.SwapMemory()
-		if err != nil {
-			log.Warningf("failed to get swap information: %v", err.Error())
+	if m.swap != nil {
+		snap := m.swap.Latest()
+		if snap.err != nil {
+			log.Warningf("failed to get swap information: %v", snap.err.Error())
 			return b
 		}
-		block.Color = GetColor(swp.UsedPercent / 100)
+		swp = snap.value.(*mem.SwapMemoryStat)
+		data.Swap = memoryStat{
+			Total:       swp.Total,
+			Available:   swp.Free,
+			Used:        swp.Used,
+			Free:        swp.Free,
+			UsedPercent: swp.UsedPercent,
+		}
+		if kind == "swap" {
+			m.config.Thresholds.Apply(block, "swap", swp.UsedPercent/100)
+		}
 	}
 
 	var ram *mem.VirtualMemoryStat
-	if strings.Split(m.config.Attribute, "_")[0] == "ram" {
-		ram, err = mem.VirtualMemory()
-		if err != nil {
-			log.Warningf("failed to get ram information: %v", err.Error())
+	if m.ram != nil {
+		snap := m.ram.Latest()
+		if snap.err != nil {
+			log.Warningf("failed to get ram information: %v", snap.err.Error())
 			return b
 		}
-		block.Color = GetColor(ram.UsedPercent / 100)
+		ram = snap.value.(*mem.VirtualMemoryStat)
+		data.RAM = memoryStat{
+			Total:       ram.Total,
+			Available:   ram.Available,
+			Used:        ram.Used,
+			Free:        ram.Free,
+			UsedPercent: ram.UsedPercent,
+		}
+		if kind == "ram" {
+			m.config.Thresholds.Apply(block, "ram", ram.UsedPercent/100)
+		}
+	}
+
+	if m.config.history.IsSet() {
+		pct := data.RAM.UsedPercent
+		if kind == "swap" {
+			pct = data.Swap.UsedPercent
+		}
+		hb := m.config.history.Blocks(kind, pct, 100, m.config.FinalSeparatorWidth, m.config.Thresholds)
+		if m.config.FinalSeparator {
+			hb[len(hb)-1].AddSeparator()
+		}
+		return append(b, hb...)
 	}
 
 	switch m.config.Attribute {
@@ -123,6 +176,7 @@ func (m *Memory) MakeBlocks() []*types.Block {
 	case "ram_string":
 		block.FullText = ram.String()
 	}
+	m.config.format.Apply(block, data)
 
 	b = append(b, block)
 
@@ -137,4 +191,9 @@ func (m *Memory) GetUpdateChan() chan []*types.Block {
 // Stop stops this module from polling and sending updated Block arrays
 func (m *Memory) Stop() {
 	close(m.Done)
+	for _, sub := range []*sampleSub{m.swap, m.ram} {
+		if sub != nil {
+			sub.Close()
+		}
+	}
 }
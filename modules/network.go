@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/dustin/go-humanize"
 	"github.com/shirou/gopsutil/net"
 	log "github.com/sirupsen/logrus"
 	"github.com/travishegner/goi3status/types"
@@ -19,14 +20,53 @@ type networkConfig struct {
 	Attribute string
 	DownSpeed int
 	UpSpeed   int
+	Units     string
+	Totals    bool
+	Packets   bool
+	Errors    bool
+	format    *textFormat
+	history   *sparkline
 }
 
-// Network is a module representing the named interface
+// networkData is the data available to Network's format templates
+type networkData struct {
+	Interface string
+	// Rx and Tx are the current rates in bits per second
+	Rx float64
+	Tx float64
+	// RxTotal and TxTotal are the byte counters of the interface
+	RxTotal uint64
+	TxTotal uint64
+	// SessionRx and SessionTx are the bytes transferred since the module started
+	SessionRx uint64
+	SessionTx uint64
+	// the remaining rates are per second
+	PacketsRx float64
+	PacketsTx float64
+	ErrorsRx  float64
+	ErrorsTx  float64
+	DropsRx   float64
+	DropsTx   float64
+}
+
+// networkSample holds the counters of a single interface when last read
+type networkSample struct {
+	stat      net.IOCountersStat
+	startRecv uint64
+	startSent uint64
+	readTime  time.Time
+}
+
+// Network is a module representing the named interface, or every interface
+// matching a glob
 type Network struct {
 	*types.BaseModule
-	config       *networkConfig
-	lastRead     uint64
-	lastReadTime time.Time
+	config   *networkConfig
+	counters *sampleSub
+	last     map[string]*networkSample
+	// lastAt is the time of the snapshot lastBlocks were made from
+	lastAt     time.Time
+	lastBlocks []*types.Block
 }
 
 func newNetworkConfig(mc types.ModuleConfig) *networkConfig {
@@ -51,12 +91,38 @@ func newNetworkConfig(mc types.ModuleConfig) *networkConfig {
 		upspd = 1000000000
 	}
 
+	units, ok := mc["units"].(string)
+	if !ok || units != "bytes" {
+		units = "bits"
+	}
+
+	totals, ok := mc["totals"].(bool)
+	if !ok {
+		totals = false
+	}
+
+	packets, ok := mc["packets"].(bool)
+	if !ok {
+		packets = false
+	}
+
+	errors, ok := mc["errors"].(bool)
+	if !ok {
+		errors = false
+	}
+
 	return &networkConfig{
 		BaseModuleConfig: bmc,
 		Interface:        iface,
 		Attribute:        attribute,
 		DownSpeed:        dnspd,
 		UpSpeed:          upspd,
+		Units:            units,
+		Totals:           totals,
+		Packets:          packets,
+		Errors:           errors,
+		format:           newTextFormat(mc),
+		history:          newSparkline(mc),
 	}
 
 }
@@ -68,21 +134,11 @@ func NewNetwork(mc types.ModuleConfig) types.Module {
 	n := &Network{
 		BaseModule: bm,
 		config:     config,
+		last:       make(map[string]*networkSample),
 	}
 
-	bm.Update <- n.MakeBlocks()
-	ticker := time.NewTicker(n.config.Refresh)
-
-	go func() {
-		for {
-			select {
-			case <-n.Done:
-				return
-			case <-ticker.C:
-				bm.Update <- n.MakeBlocks()
-			}
-		}
-	}()
+	n.counters = subscribe(netSampler(config.Interface != "all"), config.Refresh, bm.Refresh)
+	bm.Run(n.config.Refresh, n.MakeBlocks)
 
 	return n
 }
@@ -101,58 +157,180 @@ func (n *Network) MakeBlocks() []*types.Block {
 		pernic = false
 	}
 
-	stats, err := net.IOCounters(pernic)
-	if err != nil {
-		log.Errorf("failed to get network stats: %v", err.Error())
+	snap := n.counters.Latest()
+	if snap.err != nil {
+		log.Errorf("failed to get network stats: %v", snap.err.Error())
 		return b
 	}
+	// a refresh between snapshots would see no traffic at all
+	if snap.at.Equal(n.lastAt) && n.lastBlocks != nil {
+		return n.lastBlocks
+	}
+	n.lastAt = snap.at
+	stats := snap.value.([]net.IOCountersStat)
 
+	matched := make([]net.IOCountersStat, 0)
 	for _, s := range stats {
-		if !pernic || s.Name == n.config.Interface {
-			block := types.NewBlock(n.config.BlockSeparatorWidth)
-
-			bytes := uint64(0)
-			arrow := ""
-			maxSpd := int(0)
-			now := time.Now()
-			switch n.config.Attribute {
-			case "down":
-				bytes = s.BytesRecv
-				arrow = "\u2193"
-				maxSpd = n.config.DownSpeed
-			case "up":
-				bytes = s.BytesSent
-				arrow = "\u2191"
-				maxSpd = n.config.UpSpeed
-			}
-
-			bits := (bytes - n.lastRead) * 8
-			diff := now.Sub(n.lastReadTime).Seconds()
-			rawspd := float64(bits) / diff
-			spd := rawspd / 1000000
-			units := []string{"m", "g", "t"}
-			unit := 0
-			for {
-				if spd < 1000 {
-					break
-				}
-				unit++
-				spd = spd / float64(1000)
-			}
-			if n.lastRead != 0 {
-				block.FullText = fmt.Sprintf("%2.1f%s%s", spd, units[unit], arrow)
-				block.Color = GetColor(float64(rawspd) / float64(maxSpd))
-			}
-			n.lastRead = bytes
-			n.lastReadTime = now
-
-			b = append(b, block)
-
-			break
+		if !pernic || matchAny([]string{n.config.Interface}, s.Name) {
+			matched = append(matched, s)
+		}
+	}
+
+	// forget interfaces which have gone away
+	seen := make(map[string]bool)
+	for _, s := range matched {
+		seen[s.Name] = true
+	}
+	for name := range n.last {
+		if !seen[name] {
+			delete(n.last, name)
+		}
+	}
+
+	blocks := make([]*types.Block, 0)
+	for _, s := range matched {
+		last, ok := n.last[s.Name]
+		// counters start again from zero when an interface is recreated, so
+		// start over rather than wrap around
+		if !ok || netCountersReset(s, last.stat) {
+			last = &networkSample{startRecv: s.BytesRecv, startSent: s.BytesSent}
+			n.last[s.Name] = last
+		}
+		data := n.makeData(s, last, snap.at)
+		fresh := last.readTime.IsZero()
+		last.stat = s
+		last.readTime = snap.at
+		if fresh {
+			// rates need two samples
+			blocks = append(blocks, types.NewBlock(n.config.BlockSeparatorWidth))
+			continue
+		}
+
+		ib := n.makeInterfaceBlocks(data)
+		// with several interfaces, say which is which
+		if len(matched) > 1 {
+			ib[0].FullText = s.Name + " " + ib[0].FullText
+		}
+		blocks = append(blocks, ib...)
+	}
+
+	if len(blocks) > 0 {
+		block := blocks[len(blocks)-1]
+		block.SeparatorBlockWidth = n.config.FinalSeparatorWidth
+		if n.config.FinalSeparator {
+			block.AddSeparator()
+		}
+	}
+
+	n.lastBlocks = append(b, blocks...)
+	return n.lastBlocks
+}
+
+// makeData returns the data for an interface from its counters in the
+// snapshot taken at at, and those in the previous snapshot
+func (n *Network) makeData(s net.IOCountersStat, last *networkSample, at time.Time) *networkData {
+	diff := at.Sub(last.readTime).Seconds()
+	rate := func(cur, prev uint64) float64 {
+		return float64(cur-prev) / diff
+	}
+	p := last.stat
+	return &networkData{
+		Interface: s.Name,
+		Rx:        rate(s.BytesRecv, p.BytesRecv) * 8,
+		Tx:        rate(s.BytesSent, p.BytesSent) * 8,
+		RxTotal:   s.BytesRecv,
+		TxTotal:   s.BytesSent,
+		SessionRx: s.BytesRecv - last.startRecv,
+		SessionTx: s.BytesSent - last.startSent,
+		PacketsRx: rate(s.PacketsRecv, p.PacketsRecv),
+		PacketsTx: rate(s.PacketsSent, p.PacketsSent),
+		ErrorsRx:  rate(s.Errin, p.Errin),
+		ErrorsTx:  rate(s.Errout, p.Errout),
+		DropsRx:   rate(s.Dropin, p.Dropin),
+		DropsTx:   rate(s.Dropout, p.Dropout),
+	}
+}
+
+// netCountersReset returns true if any counter is lower than in prev
+func netCountersReset(cur, prev net.IOCountersStat) bool {
+	return cur.BytesRecv < prev.BytesRecv || cur.BytesSent < prev.BytesSent ||
+		cur.PacketsRecv < prev.PacketsRecv || cur.PacketsSent < prev.PacketsSent ||
+		cur.Errin < prev.Errin || cur.Errout < prev.Errout ||
+		cur.Dropin < prev.Dropin || cur.Dropout < prev.Dropout
+}
+
+// makeInterfaceBlocks returns a block for each configured direction of a
+// single interface, or one block if a format template is set
+func (n *Network) makeInterfaceBlocks(data *networkData) []*types.Block {
+	if n.config.format.IsSet() {
+		block := types.NewBlock(n.config.BlockSeparatorWidth)
+		n.config.Thresholds.Apply(block, data.Interface, data.Rx/float64(n.config.DownSpeed))
+		n.config.format.Apply(block, data)
+		return []*types.Block{block}
+	}
+
+	blocks := make([]*types.Block, 0)
+	if n.config.Attribute == "down" || n.config.Attribute == "both" {
+		block := types.NewBlock(n.config.BlockSeparatorWidth)
+		if n.config.history.IsSet() {
+			// the sparkline stands in for the rate
+			blocks = append(blocks, n.config.history.Blocks(data.Interface+"/down", data.Rx, float64(n.config.DownSpeed), 0, n.config.Thresholds)...)
+			block.FullText = "\u2193"
+		} else {
+			block.FullText = n.formatRate(data.Rx) + "\u2193"
+			n.config.Thresholds.Apply(block, data.Interface+"/down", data.Rx/float64(n.config.DownSpeed))
+		}
+		if n.config.Totals {
+			block.FullText += " " + humanize.IBytes(data.SessionRx)
+		}
+		if n.config.Packets {
+			block.FullText += fmt.Sprintf(" %vp/s", int(data.PacketsRx))
 		}
+		if n.config.Errors {
+			block.FullText += fmt.Sprintf(" %ve/s %vd/s", int(data.ErrorsRx), int(data.DropsRx))
+		}
+		blocks = append(blocks, block)
 	}
+	if n.config.Attribute == "up" || n.config.Attribute == "both" {
+		block := types.NewBlock(n.config.BlockSeparatorWidth)
+		if n.config.history.IsSet() {
+			// the sparkline stands in for the rate
+			blocks = append(blocks, n.config.history.Blocks(data.Interface+"/up", data.Tx, float64(n.config.UpSpeed), 0, n.config.Thresholds)...)
+			block.FullText = "\u2191"
+		} else {
+			block.FullText = n.formatRate(data.Tx) + "\u2191"
+			n.config.Thresholds.Apply(block, data.Interface+"/up", data.Tx/float64(n.config.UpSpeed))
+		}
+		if n.config.Totals {
+			block.FullText += " " + humanize.IBytes(data.SessionTx)
+		}
+		if n.config.Packets {
+			block.FullText += fmt.Sprintf(" %vp/s", int(data.PacketsTx))
+		}
+		if n.config.Errors {
+			block.FullText += fmt.Sprintf(" %ve/s %vd/s", int(data.ErrorsTx), int(data.DropsTx))
+		}
+		blocks = append(blocks, block)
+	}
+	return blocks
+}
 
-	return b
+// formatRate scales a rate in bits per second to the configured units,
+// e.g. 12.5m for megabits or 1.6M for megabytes
+func (n *Network) formatRate(bits float64) string {
+	spd := bits / 1000000
+	units := []string{"m", "g", "t"}
+	if n.config.Units == "bytes" {
+		spd = bits / 8 / 1000
+		units = []string{"K", "M", "G", "T"}
+	}
+
+	unit := 0
+	for spd >= 1000 && unit < len(units)-1 {
+		unit++
+		spd = spd / float64(1000)
+	}
+	return fmt.Sprintf("%2.1f%s", spd, units[unit])
 }
 
 // GetUpdateChan returns the channel down which new block arrays are sent
@@ -163,4 +341,5 @@ func (n *Network) GetUpdateChan() chan []*types.Block {
 // Stop stops this module from polling and sending updated Block arrays
 func (n *Network) Stop() {
 	close(n.Done)
+	n.counters.Close()
 }
@@ -0,0 +1,226 @@
+package modules
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/shirou/gopsutil/cpu"
+	"github.com/shirou/gopsutil/load"
+	"github.com/shirou/gopsutil/mem"
+	"github.com/shirou/gopsutil/net"
+)
+
+// sampleClock returns the time a snapshot is taken, and may be replaced
+var sampleClock = time.Now
+
+// snapshot is a single system read, and when it was taken. Rates must be
+// computed from the time of the snapshot, not the time it was looked at.
+type snapshot struct {
+	value interface{}
+	err   error
+	at    time.Time
+}
+
+// sampler reads a system statistic once per interval on behalf of every
+// module subscribed to it, at the shortest of their refresh intervals, and
+// hands each of them the same snapshot
+type sampler struct {
+	mu   sync.Mutex
+	read func() (interface{}, error)
+	last snapshot
+	subs map[*sampleSub]bool
+	// running is set while the sampler's goroutine is running
+	running bool
+	// changed wakes the sampler's goroutine when its subscribers change
+	changed chan struct{}
+}
+
+// sampleSub is a module's subscription to a sampler
+type sampleSub struct {
+	s        *sampler
+	interval time.Duration
+	// notify is called after each new snapshot is taken
+	notify func()
+}
+
+// samplers are the shared system reads, by name
+var samplers = map[string]*sampler{
+	"cpu_times":     newSampler(func() (interface{}, error) { return cpu.Times(false) }),
+	"cpu_times_per": newSampler(func() (interface{}, error) { return cpu.Times(true) }),
+	"virtual_mem":   newSampler(func() (interface{}, error) { return mem.VirtualMemory() }),
+	"swap_mem":      newSampler(func() (interface{}, error) { return mem.SwapMemory() }),
+	"load":          newSampler(func() (interface{}, error) { return load.Avg() }),
+	"net":           newSampler(func() (interface{}, error) { return net.IOCounters(false) }),
+	"net_per":       newSampler(func() (interface{}, error) { return net.IOCounters(true) }),
+}
+
+func newSampler(read func() (interface{}, error)) *sampler {
+	return &sampler{
+		read:    read,
+		subs:    make(map[*sampleSub]bool),
+		changed: make(chan struct{}, 1),
+	}
+}
+
+// subscribe registers interest in the named sampler every interval, calling
+// notify (usually the module's Refresh) whenever a new snapshot is taken.
+// The sampler runs for as long as it has subscribers.
+func subscribe(name string, interval time.Duration, notify func()) *sampleSub {
+	s := samplers[name]
+	sub := &sampleSub{s: s, interval: interval, notify: notify}
+
+	s.mu.Lock()
+	s.subs[sub] = true
+	start := !s.running
+	s.running = true
+	s.mu.Unlock()
+
+	if start {
+		go s.run()
+	} else {
+		s.wake()
+	}
+	return sub
+}
+
+// Latest returns the most recent snapshot, taking the first one if there
+// is none yet
+func (sub *sampleSub) Latest() snapshot {
+	s := sub.s
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.last.at.IsZero() {
+		s.sampleLocked()
+	}
+	return s.last
+}
+
+// Close ends the subscription
+func (sub *sampleSub) Close() {
+	s := sub.s
+	s.mu.Lock()
+	delete(s.subs, sub)
+	s.mu.Unlock()
+	s.wake()
+}
+
+func (s *sampler) wake() {
+	select {
+	case s.changed <- struct{}{}:
+	default:
+	}
+}
+
+// run takes a snapshot every time the shortest interval of the subscribers
+// has passed, until there are none left
+func (s *sampler) run() {
+	for {
+		s.mu.Lock()
+		if len(s.subs) == 0 {
+			s.running = false
+			s.mu.Unlock()
+			return
+		}
+		interval := time.Duration(math.MaxInt64)
+		for sub := range s.subs {
+			if sub.interval < interval {
+				interval = sub.interval
+			}
+		}
+		wait := interval
+		if !s.last.at.IsZero() {
+			wait -= sampleClock().Sub(s.last.at)
+		}
+		s.mu.Unlock()
+
+		select {
+		case <-s.changed:
+			continue
+		case <-time.After(wait):
+		}
+		s.sample()
+	}
+}
+
+// sample takes a new snapshot and notifies every subscriber
+func (s *sampler) sample() {
+	s.mu.Lock()
+	s.sampleLocked()
+	subs := make([]*sampleSub, 0, len(s.subs))
+	for sub := range s.subs {
+		subs = append(subs, sub)
+	}
+	s.mu.Unlock()
+
+	for _, sub := range subs {
+		if sub.notify != nil {
+			sub.notify()
+		}
+	}
+}
+
+func (s *sampler) sampleLocked() {
+	v, err := s.read()
+	s.last = snapshot{value: v, err: err, at: sampleClock()}
+}
+
+// cpuTimesSampler returns the name of the sampler of total or per cpu times
+func cpuTimesSampler(perCPU bool) string {
+	if perCPU {
+		return "cpu_times_per"
+	}
+	return "cpu_times"
+}
+
+// netSampler returns the name of the sampler of total or per nic counters
+func netSampler(pernic bool) string {
+	if pernic {
+		return "net_per"
+	}
+	return "net"
+}
+
+var (
+	cpuCountsOnce sync.Once
+	cpuCounts     int
+	cpuCountsErr  error
+)
+
+// sampleCPUCounts returns the number of physical cores, which never changes
+func sampleCPUCounts() (int, error) {
+	cpuCountsOnce.Do(func() {
+		cpuCounts, cpuCountsErr = cpu.Counts(false)
+	})
+	return cpuCounts, cpuCountsErr
+}
+
+// cpuPercents returns the busy percentage of each cpu between two sets of
+// times. Without previous times, it is the percentage since boot.
+func cpuPercents(prev, cur []cpu.TimesStat) []float64 {
+	if len(prev) != len(cur) {
+		prev = make([]cpu.TimesStat, len(cur))
+	}
+
+	pcts := make([]float64, len(cur))
+	for i := range cur {
+		all1, busy1 := cpuBusy(prev[i])
+		all2, busy2 := cpuBusy(cur[i])
+		switch {
+		case busy2 <= busy1:
+			pcts[i] = 0
+		case all2 <= all1:
+			pcts[i] = 100
+		default:
+			pcts[i] = math.Min(100, (busy2-busy1)/(all2-all1)*100)
+		}
+	}
+	return pcts
+}
+
+// cpuBusy returns the total and busy time of t. Guest time is left out, as
+// it is already counted in user.
+func cpuBusy(t cpu.TimesStat) (float64, float64) {
+	busy := t.User + t.System + t.Nice + t.Iowait + t.Irq + t.Softirq + t.Steal
+	return busy + t.Idle, busy
+}
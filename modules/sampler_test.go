@@ -0,0 +1,133 @@
+package modules
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/shirou/gopsutil/cpu"
+	"github.com/shirou/gopsutil/net"
+	"github.com/travishegner/goi3status/types"
+)
+
+// fakeClock replaces sampleClock for the rest of the test
+func fakeClock(t *testing.T) *time.Time {
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	old := sampleClock
+	sampleClock = func() time.Time { return now }
+	t.Cleanup(func() { sampleClock = old })
+	return &now
+}
+
+func TestCPUPercents(t *testing.T) {
+	tests := []struct {
+		name string
+		prev []cpu.TimesStat
+		cur  []cpu.TimesStat
+		want []float64
+	}{
+		{
+			"since boot",
+			nil,
+			[]cpu.TimesStat{{User: 10, System: 10, Idle: 60}},
+			[]float64{25},
+		},
+		{
+			"between samples",
+			[]cpu.TimesStat{{User: 10, Idle: 10}, {User: 10, Idle: 10}},
+			[]cpu.TimesStat{{User: 15, Idle: 15}, {User: 10, Iowait: 5, Steal: 5, Idle: 20}},
+			[]float64{50, 50},
+		},
+		{
+			// guest time is already in user
+			"guest",
+			[]cpu.TimesStat{{User: 10, Guest: 10, Idle: 10}},
+			[]cpu.TimesStat{{User: 20, Guest: 20, Idle: 20}},
+			[]float64{50},
+		},
+		{"idle", []cpu.TimesStat{{User: 10, Idle: 10}}, []cpu.TimesStat{{User: 10, Idle: 20}}, []float64{0}},
+		{"counters went back", []cpu.TimesStat{{User: 10, Idle: 10}}, []cpu.TimesStat{{User: 5, Idle: 20}}, []float64{0}},
+		{"only busy time passed", []cpu.TimesStat{{User: 10, Idle: 10}}, []cpu.TimesStat{{User: 20, Idle: 0}}, []float64{100}},
+		{
+			// a cpu coming online starts over from boot
+			"cpus changed",
+			[]cpu.TimesStat{{User: 10, Idle: 10}},
+			[]cpu.TimesStat{{User: 20, Idle: 20}, {User: 10, Idle: 30}},
+			[]float64{50, 25},
+		},
+	}
+	for _, tt := range tests {
+		if got := cpuPercents(tt.prev, tt.cur); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%v: cpuPercents() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSamplerShared(t *testing.T) {
+	reads := 0
+	samplers["test"] = newSampler(func() (interface{}, error) {
+		reads++
+		return reads, nil
+	})
+	defer delete(samplers, "test")
+
+	notified := make(map[int]int)
+	a := subscribe("test", time.Hour, func() { notified[0]++ })
+	b := subscribe("test", 2*time.Hour, func() { notified[1]++ })
+	defer a.Close()
+	defer b.Close()
+
+	if sa, sb := a.Latest(), b.Latest(); sa != sb || reads != 1 {
+		t.Fatalf("Latest() = %+v and %+v after %v reads, want one shared read", sa, sb, reads)
+	}
+
+	samplers["test"].sample()
+	if snap := b.Latest(); reads != 2 || snap.value != 2 {
+		t.Errorf("Latest() = %+v after %v reads, want the second read", snap, reads)
+	}
+	if notified[0] != 1 || notified[1] != 1 {
+		t.Errorf("subscribers were notified %v times, want once each", notified)
+	}
+}
+
+func TestNetworkRates(t *testing.T) {
+	now := fakeClock(t)
+	stat := net.IOCountersStat{Name: "eth0", BytesRecv: 1000, PacketsRecv: 10}
+	s := newSampler(func() (interface{}, error) {
+		return []net.IOCountersStat{stat}, nil
+	})
+	n := &Network{
+		BaseModule: types.NewBaseModule(),
+		config:     newNetworkConfig(types.ModuleConfig{"interface": "eth0", "packets": true}),
+		counters:   &sampleSub{s: s},
+		last:       make(map[string]*networkSample),
+	}
+
+	// rates need two snapshots
+	if b := n.MakeBlocks(); len(b) != 1 || b[0].FullText != "" {
+		t.Fatalf("first MakeBlocks() = %+v, want an empty block", b[0])
+	}
+
+	// the read and the refresh are a second apart, but the rate is over
+	// the two seconds between snapshots
+	*now = now.Add(2 * time.Second)
+	stat.BytesRecv += 250000
+	stat.PacketsRecv += 100
+	s.sample()
+	*now = now.Add(time.Second)
+	first := n.MakeBlocks()
+	if got, want := first[0].FullText, "1.0m↓ 50p/s"; got != want {
+		t.Errorf("MakeBlocks() = %q, want %q", got, want)
+	}
+
+	// an early refresh gets the same snapshot, and shows the same rates
+	if again := n.MakeBlocks(); again[0].FullText != first[0].FullText {
+		t.Errorf("MakeBlocks() on the same snapshot = %q, want %q", again[0].FullText, first[0].FullText)
+	}
+
+	*now = now.Add(time.Second)
+	s.sample()
+	if got, want := n.MakeBlocks()[0].FullText, "0.0m↓ 0p/s"; got != want {
+		t.Errorf("MakeBlocks() without traffic = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,183 @@
+package modules
+
+import (
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// sysfsRoot is where the thermal and hwmon classes are read from, so that
+// a synthetic tree may be used in their place
+var sysfsRoot = "/sys"
+
+// tempSensor is a single temperature reading, in degrees Celsius
+type tempSensor struct {
+	Chip  string
+	Label string
+	Temp  float64
+	// Crit and Max are the sensor's own thresholds, or 0 if it has none
+	Crit float64
+	Max  float64
+}
+
+// readTempSensors returns every temperature sensor found in the thermal
+// and hwmon classes under root
+func readTempSensors(root string) []*tempSensor {
+	sensors := readThermalZones(root)
+	return append(sensors, readHwmonTemps(root)...)
+}
+
+// readThermalZones reads /sys/class/thermal/thermal_zone*, using the zone
+// type as the chip and any critical or hot trip points as thresholds
+func readThermalZones(root string) []*tempSensor {
+	sensors := make([]*tempSensor, 0)
+	zones, _ := filepath.Glob(filepath.Join(root, "class/thermal/thermal_zone*"))
+	for _, z := range zones {
+		temp, err := readInt(filepath.Join(z, "temp"))
+		if err != nil {
+			continue
+		}
+
+		s := &tempSensor{
+			Chip:  readLine(filepath.Join(z, "type")),
+			Label: filepath.Base(z),
+			Temp:  float64(temp) / 1000,
+		}
+
+		trips, _ := filepath.Glob(filepath.Join(z, "trip_point_*_type"))
+		for _, t := range trips {
+			tt, err := readInt(strings.TrimSuffix(t, "_type") + "_temp")
+			if err != nil {
+				continue
+			}
+			switch readLine(t) {
+			case "critical":
+				s.Crit = float64(tt) / 1000
+			case "hot":
+				s.Max = float64(tt) / 1000
+			}
+		}
+
+		sensors = append(sensors, s)
+	}
+	return sensors
+}
+
+// readHwmonTemps reads the temp*_input files of /sys/class/hwmon/hwmon*,
+// using the hwmon name as the chip and temp*_label (if any) as the label
+func readHwmonTemps(root string) []*tempSensor {
+	sensors := make([]*tempSensor, 0)
+	for _, h := range hwmonDirs(root) {
+		chip := readLine(filepath.Join(h, "name"))
+		inputs, _ := filepath.Glob(filepath.Join(h, "temp*_input"))
+		for _, in := range inputs {
+			temp, err := readInt(in)
+			if err != nil {
+				continue
+			}
+
+			base := strings.TrimSuffix(in, "_input")
+			label := readLine(base + "_label")
+			if label == "" {
+				label = filepath.Base(base)
+			}
+
+			s := &tempSensor{Chip: chip, Label: label, Temp: float64(temp) / 1000}
+			if crit, err := readInt(base + "_crit"); err == nil {
+				s.Crit = float64(crit) / 1000
+			}
+			if max, err := readInt(base + "_max"); err == nil {
+				s.Max = float64(max) / 1000
+			}
+			sensors = append(sensors, s)
+		}
+	}
+	return sensors
+}
+
+// hwmonDirs returns the hwmon device directories under root. Older kernels
+// keep the sensor files in a device subdirectory.
+func hwmonDirs(root string) []string {
+	dirs := make([]string, 0)
+	hw, _ := filepath.Glob(filepath.Join(root, "class/hwmon/hwmon*"))
+	for _, h := range hw {
+		if readLine(filepath.Join(h, "name")) == "" {
+			if d := filepath.Join(h, "device"); readLine(filepath.Join(d, "name")) != "" {
+				h = d
+			}
+		}
+		dirs = append(dirs, h)
+	}
+	return dirs
+}
+
+// filterSensors returns the sensors whose chip and label match the
+// (possibly nil) regular expressions
+func filterSensors(sensors []*tempSensor, chip, label *regexp.Regexp) []*tempSensor {
+	f := make([]*tempSensor, 0)
+	for _, s := range sensors {
+		if sensorMatches(s.Chip, s.Label, chip, label) {
+			f = append(f, s)
+		}
+	}
+	return f
+}
+
+// filterFans is filterSensors for fans
+func filterFans(fans []*fanSensor, chip, label *regexp.Regexp) []*fanSensor {
+	f := make([]*fanSensor, 0)
+	for _, s := range fans {
+		if sensorMatches(s.Chip, s.Label, chip, label) {
+			f = append(f, s)
+		}
+	}
+	return f
+}
+
+// sensorMatches returns true if a sensor's chip and label match the
+// (possibly nil) regular expressions
+func sensorMatches(c, l string, chip, label *regexp.Regexp) bool {
+	return (chip == nil || chip.MatchString(c)) && (label == nil || label.MatchString(l))
+}
+
+// fanSensor is a single fan speed reading, in revolutions per minute
+type fanSensor struct {
+	Chip  string
+	Label string
+	RPM   float64
+	// Min and Max are the fan's own limits, or 0 if it has none
+	Min float64
+	Max float64
+}
+
+// readFanSensors returns every fan*_input found in the hwmon class under
+// root, using the hwmon name as the chip and fan*_label (if any) as the label
+func readFanSensors(root string) []*fanSensor {
+	fans := make([]*fanSensor, 0)
+	for _, h := range hwmonDirs(root) {
+		chip := readLine(filepath.Join(h, "name"))
+		inputs, _ := filepath.Glob(filepath.Join(h, "fan*_input"))
+		for _, in := range inputs {
+			rpm, err := readInt(in)
+			if err != nil {
+				continue
+			}
+
+			base := strings.TrimSuffix(in, "_input")
+			label := readLine(base + "_label")
+			if label == "" {
+				label = filepath.Base(base)
+			}
+
+			f := &fanSensor{Chip: chip, Label: label, RPM: float64(rpm)}
+			if min, err := readInt(base + "_min"); err == nil {
+				f.Min = float64(min)
+			}
+			if max, err := readInt(base + "_max"); err == nil {
+				f.Max = float64(max)
+			}
+			fans = append(fans, f)
+		}
+	}
+	return fans
+}
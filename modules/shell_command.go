@@ -1,13 +1,39 @@
 package modules
 
 import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
 	"os/exec"
+	"strconv"
 	"strings"
+	"sync"
+	"syscall"
 	"time"
 
+	log "github.com/sirupsen/logrus"
 	"github.com/travishegner/goi3status/types"
 )
 
+// a persistent command which exits is restarted after a delay, doubling
+// from persistMinBackoff up to persistMaxBackoff while it keeps failing
+const (
+	persistMinBackoff = time.Second
+	persistMaxBackoff = 30 * time.Second
+)
+
+// clickQueue is how many clicks may wait to be written to a persistent
+// command before more are dropped
+const clickQueue = 16
+
+// urgentExitCode is the exit code with which a command marks its output as
+// urgent
+const urgentExitCode = 33
+
 func init() {
 	addModMap("ShellCommand", NewShellCommand)
 }
@@ -16,11 +42,44 @@ func init() {
 type ShellCommand struct {
 	*types.BaseModule
 	config *shellCommandConfig
+	mu     sync.Mutex
+	// lastLine is the latest line printed by a persistent command, and
+	// clicks are waiting to be written to its stdin while it runs
+	lastLine string
+	clicks   chan []byte
+	// lastOutput and lastErr are from the latest run of the command, and
+	// ran is set once there has been one
+	lastOutput []byte
+	lastErr    error
+	ran        bool
+	// rerun asks a command run once to run again
+	rerun bool
+	// click is the click event to pass to the next run of the command
+	click *types.ClickEvent
 }
 
 type shellCommandConfig struct {
 	*types.BaseModuleConfig
-	cmd string
+	cmd     string
+	persist bool
+	// repeat runs the command again as soon as it exits
+	repeat bool
+	// once only runs the command again on a click or signal
+	once bool
+	// color and markup are used for blocks which don't set their own
+	color  string
+	markup string
+	// prefix is put before the text of the first block, like an i3blocks
+	// label
+	prefix string
+	// outputFormat is one of "raw", "i3blocks" or "json"
+	outputFormat string
+	shell        string
+	cwd          string
+	env          []string
+	// timeout is how long the command may run before it is killed, or 0
+	// for no limit
+	timeout time.Duration
 }
 
 func newShellCommandConfig(mc types.ModuleConfig) *shellCommandConfig {
@@ -30,9 +89,86 @@ func newShellCommandConfig(mc types.ModuleConfig) *shellCommandConfig {
 		cmd = ""
 	}
 
+	persist, ok := mc["persist"].(bool)
+	if !ok {
+		persist = false
+	}
+
+	repeat, ok := mc["repeat"].(bool)
+	if !ok {
+		repeat = false
+	}
+
+	once, ok := mc["once"].(bool)
+	if !ok {
+		once = false
+	}
+	if once {
+		bmc.Refresh = time.Duration(math.MaxInt64)
+	}
+
+	color, ok := mc["color"].(string)
+	if !ok {
+		color = ""
+	}
+
+	markup, ok := mc["markup"].(string)
+	if !ok {
+		markup = ""
+	}
+
+	prefix, ok := mc["prefix"].(string)
+	if !ok {
+		prefix = ""
+	}
+
+	outputFormat, ok := mc["output_format"].(string)
+	if !ok {
+		outputFormat = "raw"
+	}
+
+	shell, ok := mc["shell"].(string)
+	if !ok {
+		shell = "/bin/bash"
+	}
+
+	cwd, ok := mc["cwd"].(string)
+	if !ok {
+		cwd = ""
+	}
+
+	env := make([]string, 0)
+	envMap, _ := mc["env"].(map[interface{}]interface{})
+	for k, v := range envMap {
+		env = append(env, fmt.Sprintf("%v=%v", k, v))
+	}
+	// like i3blocks, scripts are told which block they are running for
+	if name, ok := mc["block_name"].(string); ok {
+		env = append(env, "BLOCK_NAME="+name)
+	}
+	if instance, ok := mc["instance"].(string); ok {
+		env = append(env, "BLOCK_INSTANCE="+instance)
+	}
+
+	timeout, ok := mc["timeout"].(int)
+	if !ok {
+		timeout = 0
+	}
+
 	return &shellCommandConfig{
 		BaseModuleConfig: bmc,
 		cmd:              cmd,
+		persist:          persist,
+		repeat:           repeat,
+		once:             once,
+		color:            color,
+		markup:           markup,
+		prefix:           prefix,
+		outputFormat:     outputFormat,
+		shell:            shell,
+		cwd:              cwd,
+		env:              env,
+		timeout:          time.Duration(timeout) * time.Millisecond,
 	}
 }
 
@@ -45,21 +181,179 @@ func NewShellCommand(mc types.ModuleConfig) types.Module {
 		config:     config,
 	}
 
-	bm.Update <- sc.MakeBlocks()
-	ticker := time.NewTicker(sc.config.Refresh)
+	refresh := sc.config.Refresh
+	// commands which keep running refresh the module themselves
+	if sc.config.cmd != "" && (sc.config.persist || sc.config.repeat) {
+		refresh = time.Duration(math.MaxInt64)
+	}
+	bm.Run(refresh, sc.MakeBlocks)
+	if sc.config.cmd != "" {
+		switch {
+		case sc.config.persist:
+			bm.Go(sc.stream)
+		case sc.config.repeat:
+			bm.Go(sc.repeat)
+		}
+	}
+
+	return sc
+}
+
+// stream keeps the persistent command running until the module is stopped,
+// refreshing the module with each line it prints. While the module is
+// paused, its output is left unread.
+func (sc *ShellCommand) stream() {
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-sc.Done
+		cancel()
+	}()
+
+	backoff := persistMinBackoff
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-sc.Resumed():
+		}
+
+		start := time.Now()
+		err := sc.runPersistent(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+
+		// a command which ran for a good while isn't failing repeatedly
+		if time.Since(start) > persistMaxBackoff {
+			backoff = persistMinBackoff
+		}
+		log.Warnf("persistent command %q exited (%v), restarting in %v", sc.config.cmd, err, backoff)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+		if backoff > persistMaxBackoff {
+			backoff = persistMaxBackoff
+		}
+	}
+}
+
+// repeat runs the command over and over until the module is stopped,
+// refreshing the module with the output of each run. Runs are at least
+// persistMinBackoff apart, a failing command is restarted after a growing
+// delay, and nothing is run while the module is paused.
+func (sc *ShellCommand) repeat() {
+	backoff := persistMinBackoff
+	for {
+		select {
+		case <-sc.Done:
+			return
+		case <-sc.Resumed():
+		}
+
+		start := time.Now()
+		sc.mu.Lock()
+		click := sc.click
+		sc.click = nil
+		sc.mu.Unlock()
+
+		output, err := sc.run(click)
+		select {
+		case <-sc.Done:
+			return
+		default:
+		}
+
+		sc.mu.Lock()
+		sc.lastOutput, sc.lastErr = output, err
+		sc.mu.Unlock()
+		sc.Refresh()
+
+		wait := persistMinBackoff - time.Since(start)
+		if ee, ok := err.(*exec.ExitError); err == nil || ok && ee.ExitCode() == urgentExitCode {
+			backoff = persistMinBackoff
+		} else {
+			log.Warnf("repeated command %q failed (%v), restarting in %v", sc.config.cmd, err, backoff)
+			wait = backoff
+			backoff *= 2
+			if backoff > persistMaxBackoff {
+				backoff = persistMaxBackoff
+			}
+		}
+
+		select {
+		case <-sc.Done:
+			return
+		case <-time.After(wait):
+		}
+	}
+}
+
+// runPersistent runs the command once, until it exits or ctx is done
+func (sc *ShellCommand) runPersistent(ctx context.Context) error {
+	cmd := sc.command(nil)
+	out, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	clicks := make(chan []byte, clickQueue)
+	sc.mu.Lock()
+	sc.clicks = clicks
+	sc.mu.Unlock()
+	defer func() {
+		sc.mu.Lock()
+		sc.clicks = nil
+		sc.mu.Unlock()
+	}()
 
+	exited := make(chan struct{})
+	defer close(exited)
 	go func() {
+		select {
+		case <-ctx.Done():
+			killGroup(cmd)
+		case <-exited:
+		}
+	}()
+	// a command slow to read its stdin mustn't hold up the click reader
+	sc.Go(func() {
 		for {
 			select {
-			case <-bm.Done:
+			case j := <-clicks:
+				if _, err := stdin.Write(j); err != nil {
+					log.Warnf("failed to send click to %q: %v", sc.config.cmd, err)
+				}
+			case <-exited:
 				return
-			case <-ticker.C:
-				bm.Update <- sc.MakeBlocks()
 			}
 		}
-	}()
+	})
 
-	return sc
+	scanner := bufio.NewScanner(out)
+	for scanner.Scan() {
+		sc.mu.Lock()
+		sc.lastLine = strings.TrimSpace(scanner.Text())
+		sc.mu.Unlock()
+		sc.Refresh()
+
+		select {
+		case <-ctx.Done():
+		case <-sc.Resumed():
+		}
+	}
+
+	return cmd.Wait()
 }
 
 // MakeBlocks returns the Block array for this module
@@ -71,24 +365,269 @@ func (sc *ShellCommand) MakeBlocks() []*types.Block {
 		b = append(b, block)
 	}
 
-	if sc.config.cmd != "" {
-		block := types.NewBlock(sc.config.FinalSeparatorWidth)
+	// without a command, the label is static text and the last block
+	if sc.config.cmd == "" {
+		if len(b) > 0 {
+			b[0].SeparatorBlockWidth = sc.config.FinalSeparatorWidth
+			if sc.config.FinalSeparator {
+				b[0].AddSeparator()
+			}
+		}
+		return b
+	}
+
+	var blocks []*types.Block
+	switch {
+	case sc.config.persist:
+		sc.mu.Lock()
+		blocks = sc.parseOutput(sc.lastLine)
+		sc.mu.Unlock()
+	case sc.config.repeat:
+		sc.mu.Lock()
+		output, err := sc.lastOutput, sc.lastErr
+		sc.mu.Unlock()
+		blocks = sc.outputBlocks(output, err)
+	default:
+		sc.mu.Lock()
+		click := sc.click
+		sc.click = nil
+		// a command run once shows its last output again, e.g. on Resume,
+		// unless it has been asked to run again
+		cached := sc.config.once && sc.ran && !sc.rerun
+		sc.rerun = false
+		output, err := sc.lastOutput, sc.lastErr
+		sc.mu.Unlock()
+
+		if !cached {
+			output, err = sc.run(click)
+			sc.mu.Lock()
+			sc.lastOutput, sc.lastErr, sc.ran = output, err, true
+			sc.mu.Unlock()
+		}
+		blocks = sc.outputBlocks(output, err)
+	}
+
+	if len(blocks) > 0 && sc.config.prefix != "" {
+		blocks[0].FullText = sc.config.prefix + blocks[0].FullText
+		if blocks[0].ShortText != "" {
+			blocks[0].ShortText = sc.config.prefix + blocks[0].ShortText
+		}
+	}
+
+	for _, block := range blocks {
+		if block.Color == "" {
+			block.Color = sc.config.color
+		}
+		if block.Markup == "" {
+			block.Markup = sc.config.markup
+		}
+	}
+
+	if len(blocks) > 0 {
+		block := blocks[len(blocks)-1]
+		block.SeparatorBlockWidth = sc.config.FinalSeparatorWidth
 		if sc.config.FinalSeparator {
 			block.AddSeparator()
 		}
+	}
+
+	return append(b, blocks...)
+}
+
+// outputBlocks returns the blocks for a single run of the command
+func (sc *ShellCommand) outputBlocks(output []byte, err error) []*types.Block {
+	// like i3blocks, exiting with 33 marks the output as urgent
+	urgent := false
+	if ee, ok := err.(*exec.ExitError); ok && ee.ExitCode() == urgentExitCode {
+		urgent = true
+		err = nil
+	}
+
+	if err != nil {
+		return []*types.Block{sc.errorBlock(err)}
+	}
+	blocks := sc.parseOutput(string(output))
+	for _, block := range blocks {
+		block.Urgent = block.Urgent || urgent
+	}
+	return blocks
+}
+
+// command returns the command to run, in its own process group so that
+// anything it starts can be killed along with it
+func (sc *ShellCommand) command(click *types.ClickEvent) *exec.Cmd {
+	cmd := exec.Command(sc.config.shell, "-c", sc.config.cmd)
+	cmd.Dir = sc.config.cwd
+	cmd.Env = append(os.Environ(), sc.config.env...)
+	cmd.Env = append(cmd.Env, clickEnv(click)...)
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	return cmd
+}
+
+// run runs the command to completion and returns its output, killing its
+// process group if it outlives the timeout or the module
+func (sc *ShellCommand) run(click *types.ClickEvent) ([]byte, error) {
+	cmd := sc.command(click)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
 
-		cmd := sc.config.cmd
-		output, err := exec.Command("/bin/bash", "-c", cmd).Output()
-		if err != nil {
-			block.FullText = err.Error()
-			return b
+	done := make(chan error, 1)
+	go func() {
+		done <- cmd.Wait()
+	}()
+
+	var timeout <-chan time.Time
+	if sc.config.timeout > 0 {
+		t := time.NewTimer(sc.config.timeout)
+		defer t.Stop()
+		timeout = t.C
+	}
+
+	select {
+	case err := <-done:
+		if ee, ok := err.(*exec.ExitError); ok {
+			ee.Stderr = stderr.Bytes()
 		}
+		return stdout.Bytes(), err
+	case <-timeout:
+		killGroup(cmd)
+		<-done
+		return nil, fmt.Errorf("timed out after %v", sc.config.timeout)
+	case <-sc.Done:
+		killGroup(cmd)
+		<-done
+		return nil, fmt.Errorf("stopped")
+	}
+}
 
-		block.FullText = strings.TrimSpace(string(output))
-		b = append(b, block)
+// killGroup kills every process in the group of a started command
+func killGroup(cmd *exec.Cmd) {
+	if err := syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL); err != nil {
+		log.Warnf("failed to kill %q: %v", cmd.Args, err)
+	}
+}
+
+// clickEnv returns a click as the environment variables i3blocks sets for
+// its scripts, both the current names and the older BLOCK_ ones
+func clickEnv(ce *types.ClickEvent) []string {
+	if ce == nil {
+		return nil
+	}
+	return []string{
+		"BLOCK_BUTTON=" + strconv.Itoa(ce.Button),
+		"BLOCK_X=" + strconv.Itoa(ce.X),
+		"BLOCK_Y=" + strconv.Itoa(ce.Y),
+		"button=" + strconv.Itoa(ce.Button),
+		"modifiers=" + strings.Join(ce.Modifiers, ","),
+		"x=" + strconv.Itoa(ce.X),
+		"y=" + strconv.Itoa(ce.Y),
+		"relative_x=" + strconv.Itoa(ce.RelativeX),
+		"relative_y=" + strconv.Itoa(ce.RelativeY),
+		"output_x=" + strconv.Itoa(ce.OutputX),
+		"output_y=" + strconv.Itoa(ce.OutputY),
+		"width=" + strconv.Itoa(ce.Width),
+		"height=" + strconv.Itoa(ce.Height),
+	}
+}
+
+// Click reruns the command with the click in its environment, or passes it
+// to the next run of a repeated command. A persistent command instead reads
+// the click from its stdin, as a line of json. Clicks it hasn't read yet
+// are queued, up to clickQueue, and the rest dropped.
+func (sc *ShellCommand) Click(ce *types.ClickEvent) {
+	sc.mu.Lock()
+	clicks := sc.clicks
+	if !sc.config.persist {
+		sc.click = ce
+	}
+	sc.mu.Unlock()
+
+	if !sc.config.persist {
+		sc.Refresh()
+		return
+	}
+
+	if clicks == nil {
+		return
 	}
+	j, err := json.Marshal(ce)
+	if err != nil {
+		log.Warnf("failed to encode click event: %v", err)
+		return
+	}
+	select {
+	case clicks <- append(j, '\n'):
+	default:
+		log.Warnf("dropped a click for %q, which isn't reading its clicks", sc.config.cmd)
+	}
+}
+
+// parseOutput turns the output of the command into blocks, according to
+// the configured output_format
+func (sc *ShellCommand) parseOutput(out string) []*types.Block {
+	switch sc.config.outputFormat {
+	case "json":
+		// either a single block, or an array of them
+		out = strings.TrimSpace(out)
+		raw := []json.RawMessage{json.RawMessage(out)}
+		if strings.HasPrefix(out, "[") {
+			if err := json.Unmarshal([]byte(out), &raw); err != nil {
+				return []*types.Block{sc.errorBlock(fmt.Errorf("invalid json: %v", err))}
+			}
+		}
+
+		blocks := make([]*types.Block, 0, len(raw))
+		for _, r := range raw {
+			block := types.NewBlock(sc.config.BlockSeparatorWidth)
+			if err := json.Unmarshal(r, block); err != nil {
+				return []*types.Block{sc.errorBlock(fmt.Errorf("invalid json: %v", err))}
+			}
+			blocks = append(blocks, block)
+		}
+		return blocks
+	case "i3blocks":
+		// the full text, short text and color on the first three lines
+		block := types.NewBlock(sc.config.BlockSeparatorWidth)
+		lines := strings.Split(strings.TrimRight(out, "\n"), "\n")
+		block.FullText = strings.TrimSpace(lines[0])
+		if len(lines) > 1 {
+			block.ShortText = strings.TrimSpace(lines[1])
+		}
+		if len(lines) > 2 {
+			block.Color = strings.TrimSpace(lines[2])
+		}
+		return []*types.Block{block}
+	}
+
+	block := types.NewBlock(sc.config.BlockSeparatorWidth)
+	block.FullText = strings.TrimSpace(out)
+	return []*types.Block{block}
+}
+
+// errorBlock returns a block showing why the command failed, including the
+// first line of anything it printed to stderr
+func (sc *ShellCommand) errorBlock(err error) *types.Block {
+	block := types.NewBlock(sc.config.BlockSeparatorWidth)
+	block.FullText = err.Error()
+	if ee, ok := err.(*exec.ExitError); ok {
+		if stderr := strings.TrimSpace(string(ee.Stderr)); stderr != "" {
+			block.FullText += ": " + strings.SplitN(stderr, "\n", 2)[0]
+		}
+	}
+	block.Color = "#ff0000"
+	return block
+}
 
-	return b
+// Refresh runs the command again now, even if it is only run once
+func (sc *ShellCommand) Refresh() {
+	sc.mu.Lock()
+	sc.rerun = true
+	sc.mu.Unlock()
+	sc.BaseModule.Refresh()
 }
 
 // GetUpdateChan returns the channel down which new Block arrays are sent
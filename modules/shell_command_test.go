@@ -0,0 +1,152 @@
+package modules
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/travishegner/goi3status/types"
+)
+
+// nextText returns the text of the first block sent by m within a second,
+// or "" if nothing is sent
+func nextText(t *testing.T, m types.Module) string {
+	t.Helper()
+	select {
+	case b := <-m.GetUpdateChan():
+		if len(b) == 0 {
+			return ""
+		}
+		return b[0].FullText
+	case <-time.After(time.Second):
+		return ""
+	}
+}
+
+// waitText waits up to a few seconds for m to send want
+func waitText(t *testing.T, m types.Module, want string) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	got := ""
+	for time.Now().Before(deadline) {
+		if got = nextText(t, m); got == want {
+			return
+		}
+	}
+	t.Fatalf("module sent %q, want %q", got, want)
+}
+
+func TestShellCommandPersistClicks(t *testing.T) {
+	m := NewShellCommand(types.ModuleConfig{
+		"cmd":     `echo ready; while read l; do echo "$l" | grep -o '"button":[0-9]*'; done`,
+		"persist": true,
+	})
+	defer m.Wait()
+	defer m.Stop()
+
+	waitText(t, m, "ready")
+	sc := m.(*ShellCommand)
+	sc.Click(&types.ClickEvent{Button: 3})
+	waitText(t, m, `"button":3`)
+
+	// clicks beyond the queue are dropped rather than blocking
+	sc.mu.Lock()
+	clicks := sc.clicks
+	sc.mu.Unlock()
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < 10*clickQueue; i++ {
+			sc.Click(&types.ClickEvent{Button: 1})
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Click() blocked")
+	}
+	if len(clicks) > clickQueue {
+		t.Errorf("%v clicks queued, want at most %v", len(clicks), clickQueue)
+	}
+}
+
+func TestShellCommandPersistPause(t *testing.T) {
+	m := NewShellCommand(types.ModuleConfig{
+		"cmd":     `i=0; while true; do i=$((i+1)); echo $i; sleep 0.05; done`,
+		"persist": true,
+	})
+	defer m.Wait()
+	defer m.Stop()
+	sc := m.(*ShellCommand)
+
+	waitText(t, m, "2")
+	m.Pause()
+	// the line being read when paused may still be sent
+	nextText(t, m)
+	if text := nextText(t, m); text != "" {
+		t.Fatalf("a paused module sent %q", text)
+	}
+	sc.mu.Lock()
+	paused := sc.lastLine
+	sc.mu.Unlock()
+
+	time.Sleep(200 * time.Millisecond)
+	sc.mu.Lock()
+	line := sc.lastLine
+	sc.mu.Unlock()
+	if line != paused {
+		t.Errorf("read %q while paused, after %q", line, paused)
+	}
+
+	m.Resume()
+	if text := nextText(t, m); text == "" {
+		t.Error("nothing was sent after Resume()")
+	}
+}
+
+func TestShellCommandOnce(t *testing.T) {
+	runs := filepath.Join(t.TempDir(), "runs")
+	m := NewShellCommand(types.ModuleConfig{
+		"cmd":    "echo run >> " + runs + "; wc -l < " + runs,
+		"once":   true,
+		"prefix": "runs:",
+	})
+	defer m.Wait()
+	defer m.Stop()
+
+	waitText(t, m, "runs:1")
+	// resuming shows the last output rather than running it again
+	m.Pause()
+	m.Resume()
+	waitText(t, m, "runs:1")
+	m.Refresh()
+	waitText(t, m, "runs:2")
+}
+
+func TestShellCommandRepeat(t *testing.T) {
+	runs := filepath.Join(t.TempDir(), "runs")
+	m := NewShellCommand(types.ModuleConfig{
+		"cmd":    "echo run >> " + runs + "; wc -l < " + runs,
+		"repeat": true,
+	})
+	defer m.Wait()
+	defer m.Stop()
+
+	waitText(t, m, "1")
+	// a command which exits at once still waits between runs, and isn't
+	// run at all while paused
+	m.Pause()
+	time.Sleep(2 * persistMinBackoff)
+	out, err := os.ReadFile(runs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(string(out), "run"); n != 1 {
+		t.Errorf("ran %v times while paused, want 1", n)
+	}
+
+	m.Resume()
+	waitText(t, m, "2")
+}
@@ -0,0 +1,240 @@
+package modules
+
+import (
+	"fmt"
+	"regexp"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/travishegner/goi3status/types"
+)
+
+func init() {
+	addModMap("Temperature", NewTemperature)
+}
+
+type temperatureConfig struct {
+	*types.BaseModuleConfig
+	chip      *regexp.Regexp
+	sensor    *regexp.Regexp
+	aggregate string
+	unit      string
+	tempGreen float64
+	tempRed   float64
+	format    *textFormat
+}
+
+// temperatureData is the data available to Temperature's format templates
+type temperatureData struct {
+	Chip  string
+	Label string
+	// Temp, Crit and Max are in the configured unit
+	Temp float64
+	Crit float64
+	Max  float64
+	Unit string
+}
+
+// Temperature is a module representing thermal zone and hwmon temperature sensors
+type Temperature struct {
+	*types.BaseModule
+	config *temperatureConfig
+}
+
+func newTemperatureConfig(mc types.ModuleConfig) *temperatureConfig {
+	bmc := types.NewBaseModuleConfig(mc)
+
+	agg, ok := mc["aggregate"].(string)
+	if !ok {
+		agg = "max"
+	}
+
+	unit, ok := mc["unit"].(string)
+	if !ok || unit != "F" {
+		unit = "C"
+	}
+
+	tempGreen, ok := mc["temp_green"].(int)
+	if !ok {
+		tempGreen = 40
+	}
+
+	// without temp_red, each sensor's own thresholds are used
+	tempRed, ok := mc["temp_red"].(int)
+	if !ok {
+		tempRed = 0
+	}
+
+	// sensors were once filtered by label, which is also the display label
+	// of every module, so it still filters unless sensor is set
+	sensor := configRegexp(mc, "sensor")
+	if _, ok := mc["sensor"]; !ok && bmc.Label != "" {
+		log.Warnf("Temperature filters sensors by label, which is deprecated; set sensor to filter them (or to \"\" for none)")
+		sensor = configRegexp(mc, "label")
+	}
+
+	return &temperatureConfig{
+		BaseModuleConfig: bmc,
+		chip:             configRegexp(mc, "chip"),
+		sensor:           sensor,
+		aggregate:        agg,
+		unit:             unit,
+		tempGreen:        float64(tempGreen),
+		tempRed:          float64(tempRed),
+		format:           newTextFormat(mc),
+	}
+}
+
+// NewTemperature returns the Temperature module
+func NewTemperature(mc types.ModuleConfig) types.Module {
+	config := newTemperatureConfig(mc)
+	bm := types.NewBaseModule()
+	t := &Temperature{
+		BaseModule: bm,
+		config:     config,
+	}
+
+	bm.Run(t.config.Refresh, t.MakeBlocks)
+
+	return t
+}
+
+// MakeBlocks returns the Block array for this module
+func (t *Temperature) MakeBlocks() []*types.Block {
+	b := make([]*types.Block, 0)
+	if t.config.Label != "" {
+		block := types.NewBlock(t.config.BlockSeparatorWidth)
+		block.FullText = t.config.Label
+		b = append(b, block)
+	}
+
+	sensors := filterSensors(readTempSensors(sysfsRoot), t.config.chip, t.config.sensor)
+	if len(sensors) == 0 {
+		log.Warnf("no temperature sensors matched")
+		return b
+	}
+
+	switch t.config.aggregate {
+	case "max":
+		sensors = []*tempSensor{maxSensor(sensors)}
+	case "avg":
+		sensors = []*tempSensor{avgSensor(sensors)}
+	}
+
+	blocks := make([]*types.Block, 0)
+	for _, s := range sensors {
+		blocks = append(blocks, t.makeBlock(s))
+	}
+
+	block := blocks[len(blocks)-1]
+	block.SeparatorBlockWidth = t.config.FinalSeparatorWidth
+	if t.config.FinalSeparator {
+		block.AddSeparator()
+	}
+
+	return append(b, blocks...)
+}
+
+func (t *Temperature) makeBlock(s *tempSensor) *types.Block {
+	block := types.NewBlock(t.config.BlockSeparatorWidth)
+	block.FullText = formatTemp(s.Temp, t.config.unit)
+	t.config.Thresholds.Apply(block, s.Chip+"/"+s.Label, tempRatio(s, t.config.tempGreen, t.config.tempRed))
+	t.config.format.Apply(block, &temperatureData{
+		Chip:  s.Chip,
+		Label: s.Label,
+		Temp:  convertTemp(s.Temp, t.config.unit),
+		Crit:  convertTemp(s.Crit, t.config.unit),
+		Max:   convertTemp(s.Max, t.config.unit),
+		Unit:  t.config.unit,
+	})
+	return block
+}
+
+// GetUpdateChan returns the channel down which new block arrays are sent
+func (t *Temperature) GetUpdateChan() chan []*types.Block {
+	return t.Update
+}
+
+// Stop stops this module from polling and sending updated Block arrays
+func (t *Temperature) Stop() {
+	close(t.Done)
+}
+
+// defaultTempRed is the top of the temperature scale when neither temp_red
+// nor the sensor's own thresholds set it
+const defaultTempRed = 80
+
+// tempRatio places temp on the scale from green up to red. If red is unset
+// (0), the sensor's own critical or max threshold is used instead, falling
+// back to defaultTempRed when it has neither.
+func tempRatio(s *tempSensor, green, red float64) float64 {
+	limit := red
+	switch {
+	case limit > green:
+	case s.Crit > green:
+		limit = s.Crit
+	case s.Max > green:
+		limit = s.Max
+	default:
+		limit = defaultTempRed
+	}
+	base := s.Temp - green
+	if base < 0 {
+		base = 0
+	}
+	return base / (limit - green)
+}
+
+func maxSensor(sensors []*tempSensor) *tempSensor {
+	m := sensors[0]
+	for _, s := range sensors {
+		if s.Temp > m.Temp {
+			m = s
+		}
+	}
+	return m
+}
+
+// avgSensor averages the sensors, keeping the lowest of their thresholds
+func avgSensor(sensors []*tempSensor) *tempSensor {
+	a := &tempSensor{Label: "avg"}
+	for _, s := range sensors {
+		a.Temp += s.Temp
+		if s.Crit > 0 && (a.Crit == 0 || s.Crit < a.Crit) {
+			a.Crit = s.Crit
+		}
+		if s.Max > 0 && (a.Max == 0 || s.Max < a.Max) {
+			a.Max = s.Max
+		}
+	}
+	a.Temp /= float64(len(sensors))
+	return a
+}
+
+func convertTemp(c float64, unit string) float64 {
+	if unit == "F" {
+		return c*9/5 + 32
+	}
+	return c
+}
+
+func formatTemp(c float64, unit string) string {
+	if unit == "F" {
+		return fmt.Sprintf("%v\u2109", int(convertTemp(c, unit)))
+	}
+	return fmt.Sprintf("%v\u2103", int(c))
+}
+
+// configRegexp compiles the regular expression in the named config key,
+// returning nil if it is unset or invalid
+func configRegexp(mc types.ModuleConfig, key string) *regexp.Regexp {
+	expr, ok := mc[key].(string)
+	if !ok || expr == "" {
+		return nil
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		log.Errorf("error parsing %v regular expression: %v", key, err)
+		return nil
+	}
+	return re
+}
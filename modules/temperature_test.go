@@ -0,0 +1,235 @@
+package modules
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"testing"
+
+	"github.com/travishegner/goi3status/types"
+)
+
+// writeSysfs creates files (relative path to contents) under a temporary
+// directory, and points sysfsRoot at it for the rest of the test
+func writeSysfs(t *testing.T, files map[string]string) string {
+	t.Helper()
+	root := t.TempDir()
+	for name, contents := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(contents+"\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	old := sysfsRoot
+	sysfsRoot = root
+	t.Cleanup(func() { sysfsRoot = old })
+	return root
+}
+
+// testTempTree has two thermal zones, a current hwmon device, and one with
+// the older layout keeping its files under device/
+var testTempTree = map[string]string{
+	"class/thermal/thermal_zone0/type":              "acpitz",
+	"class/thermal/thermal_zone0/temp":              "45000",
+	"class/thermal/thermal_zone0/trip_point_0_type": "critical",
+	"class/thermal/thermal_zone0/trip_point_0_temp": "95000",
+	"class/thermal/thermal_zone0/trip_point_1_type": "hot",
+	"class/thermal/thermal_zone0/trip_point_1_temp": "85000",
+	"class/thermal/thermal_zone0/trip_point_2_type": "passive",
+	"class/thermal/thermal_zone0/trip_point_2_temp": "60000",
+	"class/thermal/thermal_zone1/type":              "x86_pkg_temp",
+	"class/thermal/thermal_zone1/temp":              "52500",
+	"class/hwmon/hwmon0/name":                       "coretemp",
+	"class/hwmon/hwmon0/temp1_input":                "60000",
+	"class/hwmon/hwmon0/temp1_label":                "Package id 0",
+	"class/hwmon/hwmon0/temp1_crit":                 "100000",
+	"class/hwmon/hwmon0/temp1_max":                  "80000",
+	"class/hwmon/hwmon0/temp2_input":                "55000",
+	"class/hwmon/hwmon1/device/name":                "it87",
+	"class/hwmon/hwmon1/device/temp1_input":         "40000",
+	"class/hwmon/hwmon1/device/temp1_max":           "70000",
+}
+
+func TestReadTempSensors(t *testing.T) {
+	root := writeSysfs(t, testTempTree)
+
+	got := readTempSensors(root)
+	want := []*tempSensor{
+		{Chip: "acpitz", Label: "thermal_zone0", Temp: 45, Crit: 95, Max: 85},
+		{Chip: "x86_pkg_temp", Label: "thermal_zone1", Temp: 52.5},
+		{Chip: "coretemp", Label: "Package id 0", Temp: 60, Crit: 100, Max: 80},
+		{Chip: "coretemp", Label: "temp2", Temp: 55},
+		{Chip: "it87", Label: "temp1", Temp: 40, Max: 70},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("readTempSensors() =")
+		for _, s := range got {
+			t.Errorf("\t%+v", *s)
+		}
+	}
+}
+
+func TestFilterSensors(t *testing.T) {
+	root := writeSysfs(t, testTempTree)
+	sensors := readTempSensors(root)
+
+	tests := []struct {
+		chip   string
+		label  string
+		labels []string
+	}{
+		{"", "", []string{"thermal_zone0", "thermal_zone1", "Package id 0", "temp2", "temp1"}},
+		{"^coretemp$", "", []string{"Package id 0", "temp2"}},
+		{"", "^temp", []string{"temp2", "temp1"}},
+		{"coretemp", "^Package", []string{"Package id 0"}},
+		{"nvme", "", []string{}},
+	}
+	for _, tt := range tests {
+		var chip, label *regexp.Regexp
+		if tt.chip != "" {
+			chip = regexp.MustCompile(tt.chip)
+		}
+		if tt.label != "" {
+			label = regexp.MustCompile(tt.label)
+		}
+
+		labels := make([]string, 0)
+		for _, s := range filterSensors(sensors, chip, label) {
+			labels = append(labels, s.Label)
+		}
+		if !reflect.DeepEqual(labels, tt.labels) {
+			t.Errorf("filterSensors(%q, %q) = %q, want %q", tt.chip, tt.label, labels, tt.labels)
+		}
+	}
+}
+
+func TestMaxAndAvgSensor(t *testing.T) {
+	sensors := []*tempSensor{
+		{Label: "a", Temp: 40, Crit: 100},
+		{Label: "b", Temp: 70, Max: 90},
+		{Label: "c", Temp: 55, Crit: 95, Max: 85},
+	}
+
+	if m := maxSensor(sensors); m.Label != "b" {
+		t.Errorf("maxSensor() = %v, want b", m.Label)
+	}
+
+	// the average keeps the lowest of each threshold
+	want := &tempSensor{Label: "avg", Temp: 55, Crit: 95, Max: 85}
+	if a := avgSensor(sensors); !reflect.DeepEqual(a, want) {
+		t.Errorf("avgSensor() = %+v, want %+v", *a, *want)
+	}
+}
+
+func TestTempRatio(t *testing.T) {
+	tests := []struct {
+		name   string
+		sensor *tempSensor
+		want   float64
+	}{
+		{"crit", &tempSensor{Temp: 70, Crit: 90, Max: 80}, 0.5},
+		{"max without crit", &tempSensor{Temp: 70, Max: 80}, 2.0 / 3},
+		{"default without thresholds", &tempSensor{Temp: 70}, 2.0 / 3},
+		{"crit below green", &tempSensor{Temp: 70, Crit: 40}, 2.0 / 3},
+		{"below green", &tempSensor{Temp: 30, Crit: 90}, 0},
+	}
+	for _, tt := range tests {
+		// from 50 up to the sensor's own limit, without temp_red
+		if got := tempRatio(tt.sensor, 50, 0); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("%v: tempRatio() = %v, want %v", tt.name, got, tt.want)
+		}
+		// temp_red wins over the sensor's limits
+		want := (tt.sensor.Temp - 50) / 50
+		if want < 0 {
+			want = 0
+		}
+		if got := tempRatio(tt.sensor, 50, 100); math.Abs(got-want) > 1e-9 {
+			t.Errorf("%v: tempRatio() with temp_red = %v, want %v", tt.name, got, want)
+		}
+	}
+}
+
+func TestConvertTemp(t *testing.T) {
+	tests := []struct {
+		c    float64
+		unit string
+		want float64
+	}{
+		{0, "F", 32},
+		{100, "F", 212},
+		{-40, "F", -40},
+		{37, "C", 37},
+		{37, "", 37},
+	}
+	for _, tt := range tests {
+		if got := convertTemp(tt.c, tt.unit); got != tt.want {
+			t.Errorf("convertTemp(%v, %q) = %v, want %v", tt.c, tt.unit, got, tt.want)
+		}
+	}
+	if got := formatTemp(100, "F"); got != "212℉" {
+		t.Errorf("formatTemp(100, F) = %q", got)
+	}
+}
+
+func TestTemperatureSensorFilter(t *testing.T) {
+	tests := []struct {
+		mc   types.ModuleConfig
+		want string
+	}{
+		{types.ModuleConfig{"sensor": "^Package"}, "^Package"},
+		{types.ModuleConfig{"label": "CPU ", "sensor": "^Package"}, "^Package"},
+		{types.ModuleConfig{"label": "CPU ", "sensor": ""}, ""},
+		// the deprecated way, where label is the filter too
+		{types.ModuleConfig{"label": "^Package"}, "^Package"},
+		{types.ModuleConfig{}, ""},
+	}
+	for _, tt := range tests {
+		got := ""
+		if re := newTemperatureConfig(tt.mc).sensor; re != nil {
+			got = re.String()
+		}
+		if got != tt.want {
+			t.Errorf("newTemperatureConfig(%v).sensor = %q, want %q", tt.mc, got, tt.want)
+		}
+	}
+}
+
+func TestCPUTempBlocks(t *testing.T) {
+	writeSysfs(t, map[string]string{
+		"class/hwmon/hwmon0/name":        "coretemp",
+		"class/hwmon/hwmon0/temp1_input": "60000",
+		"class/hwmon/hwmon0/temp1_label": "Package id 0",
+		"class/hwmon/hwmon0/temp2_input": "58000",
+		"class/hwmon/hwmon0/temp2_label": "Core 0",
+		"class/hwmon/hwmon1/name":        "coretemp",
+		"class/hwmon/hwmon1/temp1_input": "71000",
+		"class/hwmon/hwmon1/temp1_label": "Package id 1",
+		"class/hwmon/hwmon1/temp2_input": "70000",
+		"class/hwmon/hwmon1/temp2_label": "Core 0",
+	})
+
+	tests := []struct {
+		mc    types.ModuleConfig
+		texts []string
+	}{
+		// a block for each socket
+		{types.ModuleConfig{"monitor": "temp"}, []string{"60℃", "71℃"}},
+		{types.ModuleConfig{"monitor": "temp", "temp_aggregate": "max"}, []string{"71℃"}},
+	}
+	for _, tt := range tests {
+		c := &CPU{BaseModule: types.NewBaseModule(), config: newCPUConfig(tt.mc)}
+		texts := make([]string, 0)
+		for _, b := range c.MakeBlocks() {
+			texts = append(texts, b.FullText)
+		}
+		if !reflect.DeepEqual(texts, tt.texts) {
+			t.Errorf("%v: MakeBlocks() = %q, want %q", tt.mc, texts, tt.texts)
+		}
+	}
+}
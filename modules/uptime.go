@@ -47,19 +47,7 @@ func NewUptime(mc types.ModuleConfig) types.Module {
 		config:     config,
 	}
 
-	bm.Update <- u.MakeBlocks()
-	ticker := time.NewTicker(u.config.Refresh)
-
-	go func() {
-		for {
-			select {
-			case <-bm.Done:
-				return
-			case <-ticker.C:
-				bm.Update <- u.MakeBlocks()
-			}
-		}
-	}()
+	bm.Run(u.config.Refresh, u.MakeBlocks)
 
 	return u
 }
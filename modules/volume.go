@@ -0,0 +1,409 @@
+package modules
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/travishegner/goi3status/types"
+)
+
+func init() {
+	addModMap("Volume", NewVolume)
+}
+
+// volumeBackend is how Volume talks to the sound server
+type volumeBackend interface {
+	// State returns the volume (in percent) and mute state of the sink
+	State() (int, bool, error)
+	// ChangeVolume raises or lowers the volume by delta percent
+	ChangeVolume(delta int) error
+	ToggleMute() error
+	// Subscribe sends on events whenever the sink may have changed, blocking
+	// until ctx is done or the subscription fails
+	Subscribe(ctx context.Context, events chan<- struct{}) error
+}
+
+// errNoSubscribe is returned by backends which can't be subscribed to
+var errNoSubscribe = errors.New("backend does not support subscribing")
+
+// volumePollInterval is how often the volume is polled when the backend
+// can't be subscribed to
+const volumePollInterval = time.Second
+
+// newVolumeBackend returns the backend of the given name, which runs cmd
+// (or the backend's own command if cmd is empty) against sink
+var newVolumeBackend = func(name, cmd, sink string) (volumeBackend, error) {
+	switch name {
+	case "pactl":
+		if cmd == "" {
+			cmd = "pactl"
+		}
+		if sink == "" {
+			sink = "@DEFAULT_SINK@"
+		}
+		return &pactlBackend{cmd: cmd, sink: sink}, nil
+	case "wpctl":
+		if cmd == "" {
+			cmd = "wpctl"
+		}
+		if sink == "" {
+			sink = "@DEFAULT_AUDIO_SINK@"
+		}
+		return &wpctlBackend{cmd: cmd, sink: sink}, nil
+	}
+	return nil, fmt.Errorf("unknown volume backend %v", name)
+}
+
+type volumeConfig struct {
+	*types.BaseModuleConfig
+	backend   string
+	command   string
+	sink      string
+	step      int
+	maxVolume int
+	format    *textFormat
+}
+
+// volumeData is the data available to Volume's format templates
+type volumeData struct {
+	Volume int
+	Muted  bool
+}
+
+// Volume is a module representing the volume of an audio sink
+type Volume struct {
+	*types.BaseModule
+	config  *volumeConfig
+	backend volumeBackend
+}
+
+func newVolumeConfig(mc types.ModuleConfig) *volumeConfig {
+	bmc := types.NewBaseModuleConfig(mc)
+
+	// updates come from the sound server, so polling is only a fallback
+	if _, ok := mc["refresh"].(int); !ok {
+		bmc.Refresh = 30 * time.Second
+	}
+
+	backend, ok := mc["backend"].(string)
+	if !ok {
+		backend = "pactl"
+	}
+
+	command, ok := mc["command"].(string)
+	if !ok {
+		command = ""
+	}
+
+	sink, ok := mc["sink"].(string)
+	if !ok {
+		sink = ""
+	}
+
+	step, ok := mc["step"].(int)
+	if !ok {
+		step = 5
+	}
+
+	maxVolume, ok := mc["max_volume"].(int)
+	if !ok {
+		maxVolume = 100
+	}
+
+	return &volumeConfig{
+		BaseModuleConfig: bmc,
+		backend:          backend,
+		command:          command,
+		sink:             sink,
+		step:             step,
+		maxVolume:        maxVolume,
+		format:           newTextFormat(mc),
+	}
+}
+
+// NewVolume returns the Volume module
+func NewVolume(mc types.ModuleConfig) types.Module {
+	config := newVolumeConfig(mc)
+	bm := types.NewBaseModule()
+	v := &Volume{
+		BaseModule: bm,
+		config:     config,
+	}
+
+	backend, err := newVolumeBackend(config.backend, config.command, config.sink)
+	if err != nil {
+		log.Errorf("failed to create volume backend: %v", err.Error())
+	}
+	v.backend = backend
+
+	bm.Run(v.config.Refresh, v.MakeBlocks)
+	if backend != nil {
+		bm.Go(v.watch)
+	}
+
+	return v
+}
+
+// watch refreshes the module on every event from the sound server, and
+// resubscribes if the subscription drops
+func (v *Volume) watch() {
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-v.Done
+		cancel()
+	}()
+
+	events := make(chan struct{}, 1)
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-events:
+				v.Refresh()
+			}
+		}
+	}()
+
+	for {
+		err := v.backend.Subscribe(ctx, events)
+		if err == errNoSubscribe {
+			v.poll(ctx, err)
+			return
+		}
+		if err != nil {
+			log.Warnf("volume subscription failed: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(5 * time.Second):
+		}
+	}
+}
+
+// poll refreshes the module often enough to follow volume changes made
+// elsewhere, unless the configured refresh is already shorter
+func (v *Volume) poll(ctx context.Context, reason error) {
+	if v.config.Refresh <= volumePollInterval {
+		log.Infof("volume is polled every %v: %v", v.config.Refresh, reason)
+		return
+	}
+	log.Infof("volume is polled every %v: %v", volumePollInterval, reason)
+
+	ticker := time.NewTicker(volumePollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			v.Refresh()
+		}
+	}
+}
+
+// MakeBlocks returns the Block array for this module
+func (v *Volume) MakeBlocks() []*types.Block {
+	b := make([]*types.Block, 0)
+	if v.config.Label != "" {
+		block := types.NewBlock(v.config.BlockSeparatorWidth)
+		block.FullText = v.config.Label
+		b = append(b, block)
+	}
+
+	if v.backend == nil {
+		return b
+	}
+
+	block := types.NewBlock(v.config.FinalSeparatorWidth)
+	if v.config.FinalSeparator {
+		block.AddSeparator()
+	}
+
+	vol, muted, err := v.backend.State()
+	if err != nil {
+		log.Warnf("failed to get volume: %v", err.Error())
+		return b
+	}
+
+	if muted {
+		block.FullText = "muted"
+		block.Color = "#808080"
+	} else {
+		block.FullText = fmt.Sprintf("%v%%", vol)
+		v.config.Thresholds.Apply(block, "volume", float64(vol)/100)
+	}
+	v.config.format.Apply(block, &volumeData{Volume: vol, Muted: muted})
+
+	b = append(b, block)
+	return b
+}
+
+// Click mutes on a left click, and changes the volume on scrolling
+func (v *Volume) Click(ce *types.ClickEvent) {
+	if v.backend == nil {
+		return
+	}
+
+	var err error
+	switch ce.Button {
+	case 1:
+		err = v.backend.ToggleMute()
+	case 4:
+		// don't step past the maximum
+		step := v.config.step
+		if vol, _, serr := v.backend.State(); serr == nil && vol+step > v.config.maxVolume {
+			step = v.config.maxVolume - vol
+		}
+		if step > 0 {
+			err = v.backend.ChangeVolume(step)
+		}
+	case 5:
+		err = v.backend.ChangeVolume(-v.config.step)
+	default:
+		return
+	}
+	if err != nil {
+		log.Warnf("failed to change volume: %v", err.Error())
+	}
+	v.Refresh()
+}
+
+// GetUpdateChan returns the channel down which new block arrays are sent
+func (v *Volume) GetUpdateChan() chan []*types.Block {
+	return v.Update
+}
+
+// Stop stops this module from polling and sending updated Block arrays
+func (v *Volume) Stop() {
+	close(v.Done)
+}
+
+// volumePercent matches the percentage of the first channel in pactl output
+var volumePercent = regexp.MustCompile(`(\d+)%`)
+
+// pactlBackend talks to PulseAudio, or PipeWire through pipewire-pulse
+type pactlBackend struct {
+	cmd  string
+	sink string
+}
+
+func (p *pactlBackend) State() (int, bool, error) {
+	out, err := exec.Command(p.cmd, "get-sink-volume", p.sink).Output()
+	if err != nil {
+		return 0, false, err
+	}
+	m := volumePercent.FindStringSubmatch(string(out))
+	if m == nil {
+		return 0, false, fmt.Errorf("no volume in %q", out)
+	}
+	vol, _ := strconv.Atoi(m[1])
+
+	out, err = exec.Command(p.cmd, "get-sink-mute", p.sink).Output()
+	if err != nil {
+		return 0, false, err
+	}
+	muted := strings.TrimSpace(string(out)) == "Mute: yes"
+
+	return vol, muted, nil
+}
+
+func (p *pactlBackend) ChangeVolume(delta int) error {
+	return exec.Command(p.cmd, "set-sink-volume", p.sink, fmt.Sprintf("%+d%%", delta)).Run()
+}
+
+func (p *pactlBackend) ToggleMute() error {
+	return exec.Command(p.cmd, "set-sink-mute", p.sink, "toggle").Run()
+}
+
+// Subscribe runs `pactl subscribe`, sending an event for each change to a
+// sink or to the server (which includes the default sink changing)
+func (p *pactlBackend) Subscribe(ctx context.Context, events chan<- struct{}) error {
+	cmd := exec.CommandContext(ctx, p.cmd, "subscribe")
+	out, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	scanner := bufio.NewScanner(out)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.Contains(line, " sink ") && !strings.Contains(line, " server") {
+			continue
+		}
+		// one pending event is enough to refresh
+		select {
+		case events <- struct{}{}:
+		default:
+		}
+	}
+
+	err = cmd.Wait()
+	if ctx.Err() != nil {
+		return nil
+	}
+	if err == nil {
+		err = fmt.Errorf("%v subscribe exited", p.cmd)
+	}
+	return err
+}
+
+// wpctlBackend talks to PipeWire through WirePlumber
+type wpctlBackend struct {
+	cmd  string
+	sink string
+}
+
+func (w *wpctlBackend) State() (int, bool, error) {
+	out, err := exec.Command(w.cmd, "get-volume", w.sink).Output()
+	if err != nil {
+		return 0, false, err
+	}
+	// Volume: 0.40 [MUTED]
+	fields := strings.Fields(string(out))
+	if len(fields) < 2 {
+		return 0, false, fmt.Errorf("no volume in %q", out)
+	}
+	vol, err := strconv.ParseFloat(fields[1], 64)
+	if err != nil {
+		return 0, false, err
+	}
+	muted := strings.Contains(string(out), "[MUTED]")
+
+	return int(vol*100 + 0.5), muted, nil
+}
+
+func (w *wpctlBackend) ChangeVolume(delta int) error {
+	sign := "+"
+	if delta < 0 {
+		sign = "-"
+		delta = -delta
+	}
+	return exec.Command(w.cmd, "set-volume", w.sink, fmt.Sprintf("%v%%%v", delta, sign)).Run()
+}
+
+func (w *wpctlBackend) ToggleMute() error {
+	return exec.Command(w.cmd, "set-mute", w.sink, "toggle").Run()
+}
+
+// Subscribe uses `pactl subscribe`, as wpctl has no way to watch for
+// changes but pipewire-pulse usually runs alongside WirePlumber
+func (w *wpctlBackend) Subscribe(ctx context.Context, events chan<- struct{}) error {
+	if err := exec.Command("pactl", "info").Run(); err != nil {
+		return errNoSubscribe
+	}
+	return (&pactlBackend{cmd: "pactl"}).Subscribe(ctx, events)
+}
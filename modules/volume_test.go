@@ -0,0 +1,160 @@
+package modules
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/travishegner/goi3status/types"
+)
+
+// stubPactl and stubWpctl print the volume and mute state written to files
+// next to them, and record every other command they're run with
+const stubPactl = `#!/bin/sh
+dir=$(dirname "$0")
+case "$1" in
+get-sink-volume)
+	v=$(cat "$dir/volume")
+	echo "Volume: front-left: 65536 / $v% / 0.00 dB,   front-right: 65536 / $v% / 0.00 dB"
+	;;
+get-sink-mute)
+	echo "Mute: $(cat "$dir/muted")"
+	;;
+*)
+	echo "$@" >> "$dir/calls"
+	;;
+esac
+`
+
+const stubWpctl = `#!/bin/sh
+dir=$(dirname "$0")
+case "$1" in
+get-volume)
+	m=""
+	[ "$(cat "$dir/muted")" = yes ] && m=" [MUTED]"
+	echo "Volume: $(cat "$dir/volume")$m"
+	;;
+*)
+	echo "$@" >> "$dir/calls"
+	;;
+esac
+`
+
+// stubVolume is a stub command with its state files
+type stubVolume struct {
+	t   *testing.T
+	dir string
+	cmd string
+}
+
+func newStubVolume(t *testing.T, name, script string) *stubVolume {
+	dir := t.TempDir()
+	s := &stubVolume{t: t, dir: dir, cmd: filepath.Join(dir, name)}
+	if err := os.WriteFile(s.cmd, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func (s *stubVolume) set(volume, muted string) {
+	for name, v := range map[string]string{"volume": volume, "muted": muted, "calls": ""} {
+		if err := os.WriteFile(filepath.Join(s.dir, name), []byte(v), 0644); err != nil {
+			s.t.Fatal(err)
+		}
+	}
+}
+
+func (s *stubVolume) calls() []string {
+	out, err := os.ReadFile(filepath.Join(s.dir, "calls"))
+	if err != nil {
+		s.t.Fatal(err)
+	}
+	calls := make([]string, 0)
+	for _, l := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		if l != "" {
+			calls = append(calls, l)
+		}
+	}
+	return calls
+}
+
+func TestVolumeBackendState(t *testing.T) {
+	pactl := newStubVolume(t, "pactl", stubPactl)
+	wpctl := newStubVolume(t, "wpctl", stubWpctl)
+
+	tests := []struct {
+		backend string
+		stub    *stubVolume
+		volume  string
+		muted   string
+		wantVol int
+		wantMut bool
+	}{
+		{"pactl", pactl, "40", "no", 40, false},
+		{"pactl", pactl, "100", "yes", 100, true},
+		{"wpctl", wpctl, "0.40", "no", 40, false},
+		{"wpctl", wpctl, "0.555", "yes", 56, true},
+	}
+	for _, tt := range tests {
+		tt.stub.set(tt.volume, tt.muted)
+		backend, err := newVolumeBackend(tt.backend, tt.stub.cmd, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		vol, muted, err := backend.State()
+		if err != nil {
+			t.Errorf("%v %v: State() failed: %v", tt.backend, tt.volume, err)
+			continue
+		}
+		if vol != tt.wantVol || muted != tt.wantMut {
+			t.Errorf("%v %v: State() = %v, %v, want %v, %v",
+				tt.backend, tt.volume, vol, muted, tt.wantVol, tt.wantMut)
+		}
+	}
+}
+
+func TestVolumeClick(t *testing.T) {
+	pactl := newStubVolume(t, "pactl", stubPactl)
+	wpctl := newStubVolume(t, "wpctl", stubWpctl)
+
+	tests := []struct {
+		backend string
+		stub    *stubVolume
+		volume  string
+		button  int
+		calls   []string
+	}{
+		{"pactl", pactl, "50", 4, []string{"set-sink-volume @DEFAULT_SINK@ +5%"}},
+		// scrolling up stops at max_volume
+		{"pactl", pactl, "97", 4, []string{"set-sink-volume @DEFAULT_SINK@ +3%"}},
+		{"pactl", pactl, "100", 4, []string{}},
+		{"pactl", pactl, "100", 5, []string{"set-sink-volume @DEFAULT_SINK@ -5%"}},
+		{"pactl", pactl, "50", 1, []string{"set-sink-mute @DEFAULT_SINK@ toggle"}},
+		{"wpctl", wpctl, "0.50", 4, []string{"set-volume @DEFAULT_AUDIO_SINK@ 5%+"}},
+		{"wpctl", wpctl, "0.98", 4, []string{"set-volume @DEFAULT_AUDIO_SINK@ 2%+"}},
+		{"wpctl", wpctl, "1.00", 4, []string{}},
+		{"wpctl", wpctl, "1.00", 5, []string{"set-volume @DEFAULT_AUDIO_SINK@ 5%-"}},
+		{"wpctl", wpctl, "0.50", 1, []string{"set-mute @DEFAULT_AUDIO_SINK@ toggle"}},
+	}
+	for _, tt := range tests {
+		tt.stub.set(tt.volume, "no")
+		backend, err := newVolumeBackend(tt.backend, tt.stub.cmd, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		v := &Volume{
+			BaseModule: types.NewBaseModule(),
+			config:     newVolumeConfig(types.ModuleConfig{"max_volume": 100}),
+			backend:    backend,
+		}
+
+		v.Click(&types.ClickEvent{Button: tt.button})
+		if calls := tt.stub.calls(); !reflect.DeepEqual(calls, tt.calls) {
+			t.Errorf("%v at %v, button %v: ran %q, want %q",
+				tt.backend, tt.volume, tt.button, calls, tt.calls)
+		}
+	}
+}
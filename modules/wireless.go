@@ -0,0 +1,294 @@
+package modules
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/travishegner/goi3status/types"
+)
+
+func init() {
+	addModMap("Wireless", NewWireless)
+}
+
+// iwLink returns the output of `iw dev <iface> link`, which reports the
+// nl80211 association of an interface
+var iwLink = func(iface string) (string, error) {
+	out, err := exec.Command("iw", "dev", iface, "link").Output()
+	return string(out), err
+}
+
+// procNetWireless is where the link quality of wireless interfaces is read
+var procNetWireless = "/proc/net/wireless"
+
+type wirelessConfig struct {
+	*types.BaseModuleConfig
+	Interface string
+	format    *textFormat
+}
+
+// wirelessData is the data available to Wireless's format templates
+type wirelessData struct {
+	Interface string
+	// Down is set when the interface isn't listed in /proc/net/wireless,
+	// e.g. because its radio is off
+	Down      bool
+	Connected bool
+	SSID      string
+	// Quality is the link quality as a percentage
+	Quality float64
+	// Signal is the signal level in dBm
+	Signal float64
+	// Bitrate is the transmit bitrate in Mbit/s
+	Bitrate float64
+	// Frequency is in MHz, and Band is its band e.g. 5GHz
+	Frequency int
+	Band      string
+}
+
+// Wireless is a module representing the association of a wireless interface
+type Wireless struct {
+	*types.BaseModule
+	config *wirelessConfig
+	// iwFailed and procFailed are set while iw can't be run or
+	// /proc/net/wireless can't be read, so that each is only warned about once
+	iwFailed   bool
+	procFailed bool
+}
+
+func newWirelessConfig(mc types.ModuleConfig) *wirelessConfig {
+	bmc := types.NewBaseModuleConfig(mc)
+
+	iface, ok := mc["interface"].(string)
+	if !ok {
+		iface = ""
+	}
+
+	// a strong signal is good, so the scale runs the other way by default
+	if _, ok := mc["invert"].(bool); !ok {
+		bmc.Thresholds.Invert = true
+	}
+
+	return &wirelessConfig{
+		BaseModuleConfig: bmc,
+		Interface:        iface,
+		format:           newTextFormat(mc),
+	}
+}
+
+// NewWireless returns the Wireless module
+func NewWireless(mc types.ModuleConfig) types.Module {
+	config := newWirelessConfig(mc)
+	bm := types.NewBaseModule()
+	w := &Wireless{
+		BaseModule: bm,
+		config:     config,
+	}
+
+	bm.Run(w.config.Refresh, w.MakeBlocks)
+
+	return w
+}
+
+// MakeBlocks returns the Block array for this module
+func (w *Wireless) MakeBlocks() []*types.Block {
+	b := make([]*types.Block, 0)
+	if w.config.Label != "" {
+		block := types.NewBlock(w.config.BlockSeparatorWidth)
+		block.FullText = w.config.Label
+		b = append(b, block)
+	}
+
+	block := types.NewBlock(w.config.FinalSeparatorWidth)
+	if w.config.FinalSeparator {
+		block.AddSeparator()
+	}
+
+	data, err := w.readWireless()
+	if err != nil {
+		if !w.procFailed {
+			log.Warningf("failed to get wireless information: %v", err.Error())
+			w.procFailed = true
+		}
+		data = &wirelessData{Interface: w.config.Interface, Down: true}
+	} else {
+		w.procFailed = false
+	}
+
+	switch {
+	case data.Down:
+		block.FullText = strings.TrimSpace(data.Interface + " down")
+		block.Urgent = true
+		// the bad end of the scale, which is usually inverted
+		worst := 1.0
+		if w.config.Thresholds.Invert {
+			worst = 0
+		}
+		w.config.Thresholds.Apply(block, data.Interface, worst)
+	case !data.Connected:
+		block.FullText = "disconnected"
+		w.config.Thresholds.Apply(block, data.Interface, 0)
+	default:
+		block.FullText = fmt.Sprintf("%v%%", int(data.Quality))
+		if data.SSID != "" {
+			block.FullText = data.SSID + " " + block.FullText
+		}
+		w.config.Thresholds.Apply(block, data.Interface, data.Quality/100)
+	}
+	w.config.format.Apply(block, data)
+
+	b = append(b, block)
+	return b
+}
+
+// readWireless combines the link quality from /proc/net/wireless with the
+// association details reported by iw
+func (w *Wireless) readWireless() (*wirelessData, error) {
+	quals, ifaces, err := readProcWireless(procNetWireless)
+	if err != nil {
+		return nil, err
+	}
+
+	iface := w.config.Interface
+	if iface == "" && len(ifaces) > 0 {
+		iface = ifaces[0]
+	}
+
+	data := &wirelessData{Interface: iface}
+	q, ok := quals[iface]
+	if !ok {
+		// the interface is down, or not wireless at all
+		data.Down = true
+		return data, nil
+	}
+
+	out, err := iwLink(iface)
+	if err != nil {
+		// without iw the SSID is unknown, but any link quality means the
+		// interface is associated
+		if !w.iwFailed {
+			log.Warnf("failed to run iw, using /proc/net/wireless only: %v", err)
+			w.iwFailed = true
+		}
+		data.Connected = q.link > 0
+	} else {
+		w.iwFailed = false
+		parseIwLink(out, data)
+	}
+	if !data.Connected {
+		return data, nil
+	}
+
+	// quality is reported out of 70 by most drivers
+	data.Quality = q.link / 70 * 100
+	if data.Quality > 100 {
+		data.Quality = 100
+	}
+	if data.Signal == 0 {
+		data.Signal = q.level
+	}
+	return data, nil
+}
+
+// GetUpdateChan returns the channel down which new block arrays are sent
+func (w *Wireless) GetUpdateChan() chan []*types.Block {
+	return w.Update
+}
+
+// Stop stops this module from polling and sending updated Block arrays
+func (w *Wireless) Stop() {
+	close(w.Done)
+}
+
+// wirelessQuality is a single interface's line of /proc/net/wireless
+type wirelessQuality struct {
+	link  float64
+	level float64
+}
+
+// readProcWireless parses a file in the format of /proc/net/wireless,
+// returning the quality by interface along with the interfaces in file order
+func readProcWireless(path string) (map[string]*wirelessQuality, []string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+
+	quals := make(map[string]*wirelessQuality)
+	ifaces := make([]string, 0)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		parts := strings.SplitN(scanner.Text(), ":", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		fields := strings.Fields(parts[1])
+		if len(fields) < 4 {
+			continue
+		}
+		// values may have a trailing "." marking them as updated
+		link, err := strconv.ParseFloat(strings.TrimSuffix(fields[1], "."), 64)
+		if err != nil {
+			continue
+		}
+		level, _ := strconv.ParseFloat(strings.TrimSuffix(fields[2], "."), 64)
+
+		iface := strings.TrimSpace(parts[0])
+		quals[iface] = &wirelessQuality{link: link, level: level}
+		ifaces = append(ifaces, iface)
+	}
+
+	return quals, ifaces, scanner.Err()
+}
+
+// parseIwLink fills in data from the output of `iw dev <iface> link`
+func parseIwLink(out string, data *wirelessData) {
+	for _, line := range strings.Split(out, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "Connected to") {
+			data.Connected = true
+			continue
+		}
+
+		kv := strings.SplitN(line, ":", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		val := strings.TrimSpace(kv[1])
+		fields := strings.Fields(val)
+		switch kv[0] {
+		case "SSID":
+			data.SSID = val
+		case "freq":
+			f, _ := strconv.ParseFloat(val, 64)
+			data.Frequency = int(f)
+			data.Band = wirelessBand(data.Frequency)
+		case "signal":
+			if len(fields) > 0 {
+				data.Signal, _ = strconv.ParseFloat(fields[0], 64)
+			}
+		case "tx bitrate":
+			if len(fields) > 0 {
+				data.Bitrate, _ = strconv.ParseFloat(fields[0], 64)
+			}
+		}
+	}
+}
+
+func wirelessBand(freq int) string {
+	switch {
+	case freq >= 5925:
+		return "6GHz"
+	case freq >= 4900:
+		return "5GHz"
+	case freq > 0:
+		return "2.4GHz"
+	}
+	return ""
+}
@@ -0,0 +1,93 @@
+package modules
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/travishegner/goi3status/types"
+)
+
+const testProcWireless = `Inter-| sta-|   Quality        |   Discarded packets               | Missed | WE
+ face | tus | link level noise |  nwid  crypt   frag  retry   misc | beacon | 22
+wlp3s0: 0000   35.  -62.  -256        0      0      0      0    144        0
+`
+
+const testIwLink = `Connected to 00:11:22:33:44:55 (on wlp3s0)
+	SSID: home network
+	freq: 5180
+	RX: 1234 bytes (10 packets)
+	TX: 567 bytes (5 packets)
+	signal: -58 dBm
+	tx bitrate: 433.3 MBit/s VHT-MCS 9 80MHz short GI VHT-NSS 1
+`
+
+func TestParseIwLink(t *testing.T) {
+	data := &wirelessData{}
+	parseIwLink(testIwLink, data)
+	want := wirelessData{Connected: true, SSID: "home network", Signal: -58, Bitrate: 433.3, Frequency: 5180, Band: "5GHz"}
+	if *data != want {
+		t.Errorf("parseIwLink() = %+v, want %+v", *data, want)
+	}
+
+	data = &wirelessData{}
+	parseIwLink("Not connected.\n", data)
+	if data.Connected {
+		t.Errorf("parseIwLink() connected without an association")
+	}
+}
+
+func TestReadWireless(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "wireless")
+	if err := os.WriteFile(path, []byte(testProcWireless), 0644); err != nil {
+		t.Fatal(err)
+	}
+	oldProc, oldIw := procNetWireless, iwLink
+	procNetWireless = path
+	defer func() { procNetWireless, iwLink = oldProc, oldIw }()
+
+	tests := []struct {
+		name  string
+		iface string
+		iw    func(string) (string, error)
+		want  wirelessData
+	}{
+		{
+			name: "iw",
+			iw:   func(string) (string, error) { return testIwLink, nil },
+			want: wirelessData{Interface: "wlp3s0", Connected: true, SSID: "home network", Quality: 50,
+				Signal: -58, Bitrate: 433.3, Frequency: 5180, Band: "5GHz"},
+		},
+		{
+			name: "without iw",
+			iw:   func(string) (string, error) { return "", errors.New("executable file not found") },
+			want: wirelessData{Interface: "wlp3s0", Connected: true, Quality: 50, Signal: -62},
+		},
+		{
+			name:  "radio off",
+			iface: "wlan1",
+			iw:    func(string) (string, error) { return "", errors.New("no such device") },
+			want:  wirelessData{Interface: "wlan1", Down: true},
+		},
+	}
+	for _, tt := range tests {
+		iwLink = tt.iw
+		w := &Wireless{config: newWirelessConfig(types.ModuleConfig{"interface": tt.iface})}
+		data, err := w.readWireless()
+		if err != nil {
+			t.Errorf("%v: readWireless() failed: %v", tt.name, err)
+			continue
+		}
+		if *data != tt.want {
+			t.Errorf("%v: readWireless() = %+v, want %+v", tt.name, *data, tt.want)
+		}
+	}
+
+	// a missing interface still gets a block
+	w := &Wireless{BaseModule: types.NewBaseModule(), config: newWirelessConfig(types.ModuleConfig{"interface": "wlan1"})}
+	blocks := w.MakeBlocks()
+	if len(blocks) != 1 || blocks[0].FullText != "wlan1 down" || !blocks[0].Urgent {
+		t.Errorf("MakeBlocks() for a missing interface = %+v", blocks)
+	}
+}
@@ -0,0 +1,34 @@
+package notify
+
+import "sync"
+
+// Crossings remembers the level each tracked value was last at, so that an
+// alert fires once when a value crosses into a higher level rather than on
+// every refresh. Falling back to a lower level re-arms the higher ones.
+type Crossings struct {
+	mu     sync.Mutex
+	levels map[string]int
+}
+
+// NewCrossings returns an empty Crossings
+func NewCrossings() *Crossings {
+	return &Crossings{levels: make(map[string]int)}
+}
+
+// Cross records level for key, returning true if it is higher than the
+// previous level. A key starts at level 0.
+func (c *Crossings) Cross(key string, level int) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	prev := c.levels[key]
+	c.levels[key] = level
+	return level > prev
+}
+
+// Level returns the level last recorded for key
+func (c *Crossings) Level(key string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.levels[key]
+}
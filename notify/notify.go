@@ -0,0 +1,166 @@
+package notify
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+	log "github.com/sirupsen/logrus"
+)
+
+// appName is the application notifications are sent as
+const appName = "goi3status"
+
+// Urgency is the urgency level of a notification, as in the desktop
+// notifications spec
+type Urgency byte
+
+// The urgency levels of the desktop notifications spec
+const (
+	Low Urgency = iota
+	Normal
+	Critical
+)
+
+func (u Urgency) String() string {
+	switch u {
+	case Low:
+		return "low"
+	case Critical:
+		return "critical"
+	}
+	return "normal"
+}
+
+// Notification is a single desktop notification
+type Notification struct {
+	Summary string
+	Body    string
+	Icon    string
+	Urgency Urgency
+	// Timeout is how long the notification is shown, or 0 for the server's
+	// default
+	Timeout time.Duration
+}
+
+// Notifier sends desktop notifications
+type Notifier interface {
+	Notify(*Notification) error
+}
+
+// DBus sends notifications to the org.freedesktop.Notifications service
+type DBus struct {
+	conn *dbus.Conn
+}
+
+// NewDBus returns a DBus notifier which uses conn
+func NewDBus(conn *dbus.Conn) *DBus {
+	return &DBus{conn: conn}
+}
+
+// Notify sends n to the notification daemon
+func (d *DBus) Notify(n *Notification) error {
+	timeout := int32(-1)
+	if n.Timeout > 0 {
+		timeout = int32(n.Timeout / time.Millisecond)
+	}
+
+	obj := d.conn.Object("org.freedesktop.Notifications", "/org/freedesktop/Notifications")
+	call := obj.Call("org.freedesktop.Notifications.Notify", 0,
+		appName,
+		uint32(0),
+		n.Icon,
+		n.Summary,
+		n.Body,
+		[]string{},
+		map[string]dbus.Variant{"urgency": dbus.MakeVariant(byte(n.Urgency))},
+		timeout,
+	)
+	return call.Err
+}
+
+// Command sends notifications by running notify-send, or a compatible
+// command
+type Command struct {
+	cmd string
+}
+
+// NewCommand returns a Command notifier which runs cmd
+func NewCommand(cmd string) *Command {
+	return &Command{cmd: cmd}
+}
+
+// Notify runs the command to send n
+func (c *Command) Notify(n *Notification) error {
+	args := []string{"-a", appName, "-u", n.Urgency.String()}
+	if n.Icon != "" {
+		args = append(args, "-i", n.Icon)
+	}
+	if n.Timeout > 0 {
+		args = append(args, "-t", strconv.FormatInt(int64(n.Timeout/time.Millisecond), 10))
+	}
+	args = append(args, n.Summary, n.Body)
+
+	out, err := exec.Command(c.cmd, args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%v: %s", err, out)
+	}
+	return nil
+}
+
+var (
+	defaultOnce     sync.Once
+	defaultNotifier Notifier
+)
+
+// sessionBus connects to the session bus, and may be replaced to use
+// another bus
+var sessionBus = dbus.SessionBus
+
+// Default returns the D-Bus notifier if the session bus is reachable, or
+// else one which runs notify-send
+func Default() Notifier {
+	defaultOnce.Do(func() {
+		conn, err := sessionBus()
+		if err != nil {
+			log.Infof("sending notifications with notify-send: %v", err)
+			defaultNotifier = NewCommand("notify-send")
+			return
+		}
+		defaultNotifier = &fallback{
+			dbus:     NewDBus(conn),
+			fallback: NewCommand("notify-send"),
+		}
+	})
+	return defaultNotifier
+}
+
+// fallback sends notifications over D-Bus, unless no notification daemon
+// is running on the bus, in which case it runs a command instead
+type fallback struct {
+	dbus     *DBus
+	fallback Notifier
+}
+
+func (f *fallback) Notify(n *Notification) error {
+	err := f.dbus.Notify(n)
+	var derr dbus.Error
+	if errors.As(err, &derr) && derr.Name == "org.freedesktop.DBus.Error.ServiceUnknown" {
+		return f.fallback.Notify(n)
+	}
+	return err
+}
+
+// Send sends n with the default notifier in the background, so that a slow
+// notification daemon never holds up a module
+func Send(n *Notification) {
+	go func() {
+		if err := Default().Notify(n); err != nil {
+			log.Warnf("failed to send notification: %v", err)
+		}
+	}()
+}
@@ -0,0 +1,164 @@
+package notify
+
+import (
+	"bufio"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// fakeDaemon stands in for a notification daemon, passing on each call
+type fakeDaemon struct {
+	calls chan []interface{}
+}
+
+func (f *fakeDaemon) Notify(app string, id uint32, icon, summary, body string, actions []string,
+	hints map[string]dbus.Variant, timeout int32) (uint32, *dbus.Error) {
+	f.calls <- []interface{}{app, icon, summary, body, hints["urgency"].Value(), timeout}
+	return 1, nil
+}
+
+// privateBus starts a session bus of its own, and points sessionBus at it
+// for the rest of the test
+func privateBus(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("dbus-daemon"); err != nil {
+		t.Skip("dbus-daemon is not installed")
+	}
+
+	cmd := exec.Command("dbus-daemon", "--session", "--nofork", "--print-address")
+	out, err := cmd.StdoutPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		cmd.Process.Kill()
+		cmd.Wait()
+	})
+	addr, err := bufio.NewReader(out).ReadString('\n')
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr = strings.TrimSpace(addr)
+
+	old := sessionBus
+	sessionBus = func() (*dbus.Conn, error) { return dbus.Connect(addr) }
+	resetDefault()
+	t.Cleanup(func() {
+		sessionBus = old
+		resetDefault()
+	})
+	return addr
+}
+
+func resetDefault() {
+	defaultOnce = sync.Once{}
+	defaultNotifier = nil
+}
+
+func TestDBusNotify(t *testing.T) {
+	addr := privateBus(t)
+
+	conn, err := dbus.Connect(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	daemon := &fakeDaemon{calls: make(chan []interface{}, 1)}
+	if err := conn.Export(daemon, "/org/freedesktop/Notifications", "org.freedesktop.Notifications"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := conn.RequestName("org.freedesktop.Notifications", dbus.NameFlagDoNotQueue); err != nil {
+		t.Fatal(err)
+	}
+
+	err = Default().Notify(&Notification{
+		Summary: "Battery low",
+		Body:    "10% remaining",
+		Icon:    "battery-low",
+		Urgency: Critical,
+		Timeout: 5 * time.Second,
+	})
+	if err != nil {
+		t.Fatalf("Notify() failed: %v", err)
+	}
+
+	want := []interface{}{appName, "battery-low", "Battery low", "10% remaining", byte(Critical), int32(5000)}
+	select {
+	case got := <-daemon.calls:
+		for i := range want {
+			if got[i] != want[i] {
+				t.Errorf("Notify() sent %v, want %v", got, want)
+				break
+			}
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the daemon was never called")
+	}
+}
+
+func TestDefaultWithoutDaemon(t *testing.T) {
+	privateBus(t)
+
+	// nothing owns org.freedesktop.Notifications, so notify-send is run
+	dir := t.TempDir()
+	script := "#!/bin/sh\necho \"$@\" > " + filepath.Join(dir, "args") + "\n"
+	if err := os.WriteFile(filepath.Join(dir, "notify-send"), []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	path := os.Getenv("PATH")
+	os.Setenv("PATH", dir+string(os.PathListSeparator)+path)
+	defer os.Setenv("PATH", path)
+
+	if err := Default().Notify(&Notification{Summary: "Battery low", Body: "5% remaining"}); err != nil {
+		t.Fatalf("Notify() failed: %v", err)
+	}
+
+	args, err := os.ReadFile(filepath.Join(dir, "args"))
+	if err != nil {
+		t.Fatalf("notify-send was not run: %v", err)
+	}
+	if want := "-a goi3status -u low Battery low 5% remaining\n"; string(args) != want {
+		t.Errorf("notify-send was run with %q, want %q", args, want)
+	}
+}
+
+func TestCrossings(t *testing.T) {
+	c := NewCrossings()
+	steps := []struct {
+		key   string
+		level int
+		want  bool
+	}{
+		{"BAT0", 0, false},
+		{"BAT0", 1, true},
+		// staying at a level doesn't fire again
+		{"BAT0", 1, false},
+		{"BAT0", 2, true},
+		{"BAT0", 2, false},
+		// keys are tracked separately
+		{"BAT1", 2, true},
+		// recovering re-arms the higher levels
+		{"BAT0", 0, false},
+		{"BAT0", 2, true},
+		{"BAT0", 1, false},
+		{"BAT0", 2, true},
+	}
+	for i, s := range steps {
+		if got := c.Cross(s.key, s.level); got != s.want {
+			t.Errorf("step %v: Cross(%v, %v) = %v, want %v", i, s.key, s.level, got, s.want)
+		}
+	}
+	if c.Level("BAT0") != 2 || c.Level("BAT2") != 0 {
+		t.Errorf("Level() = %v and %v, want 2 and 0", c.Level("BAT0"), c.Level("BAT2"))
+	}
+}
@@ -0,0 +1,53 @@
+package outputs
+
+import (
+	"strings"
+
+	"github.com/travishegner/goi3status/types"
+)
+
+func init() {
+	addOutputMap("dzen2", NewDzen2)
+}
+
+// Dzen2 is text with ^fg(#rrggbb) style formatting commands, as understood by dzen2
+type Dzen2 struct{}
+
+// NewDzen2 returns the dzen2 output
+func NewDzen2() types.Output {
+	return &Dzen2{}
+}
+
+// Header returns nothing, dzen2 has no header
+func (o *Dzen2) Header(c *types.Config) (string, error) {
+	return "", nil
+}
+
+// Frame returns the Block array as a single line of formatted text
+func (o *Dzen2) Frame(blocks []*types.Block) (string, error) {
+	return joinBlocks(blocks, o.formatBlock), nil
+}
+
+func (o *Dzen2) formatBlock(b *types.Block) string {
+	text := strings.ReplaceAll(b.FullText, "^", "^^")
+	fg := b.Color
+	bg := b.Background
+	if b.Urgent {
+		fg, bg = bg, fg
+		if bg == "" {
+			bg = "#ff0000"
+		}
+	}
+	if fg != "" {
+		text = "^fg(" + fg + ")" + text + "^fg()"
+	}
+	if bg != "" {
+		text = "^bg(" + bg + ")" + text + "^bg()"
+	}
+	return text
+}
+
+// Footer returns nothing, dzen2 has no footer
+func (o *Dzen2) Footer() string {
+	return ""
+}
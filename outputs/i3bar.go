@@ -0,0 +1,47 @@
+package outputs
+
+import (
+	"encoding/json"
+
+	"github.com/travishegner/goi3status/types"
+)
+
+func init() {
+	addOutputMap("i3bar", NewI3bar)
+	addOutputMap("swaybar", NewI3bar)
+}
+
+// I3bar is the JSON protocol spoken by i3bar and swaybar: https://i3wm.org/docs/i3bar-protocol.html
+type I3bar struct {
+	sep string
+}
+
+// NewI3bar returns the i3bar output
+func NewI3bar() types.Output {
+	return &I3bar{}
+}
+
+// Header returns the protocol header and the start of the infinite array
+func (o *I3bar) Header(c *types.Config) (string, error) {
+	j, err := json.Marshal(c)
+	if err != nil {
+		return "", err
+	}
+	return string(j) + "\n[", nil
+}
+
+// Frame returns the Block array as a JSON array
+func (o *I3bar) Frame(blocks []*types.Block) (string, error) {
+	j, err := json.Marshal(blocks)
+	if err != nil {
+		return "", err
+	}
+	f := o.sep + string(j)
+	o.sep = ","
+	return f, nil
+}
+
+// Footer closes the infinite array
+func (o *I3bar) Footer() string {
+	return "]"
+}
@@ -0,0 +1,74 @@
+package outputs
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/travishegner/goi3status/types"
+)
+
+var outputs = make(map[string]types.CreateOutput)
+
+func addOutputMap(name string, newFunc types.CreateOutput) {
+	outputs[name] = newFunc
+}
+
+// GetOutput returns a newly created output based on it's configuration name
+func GetOutput(name string) (types.Output, error) {
+	co, ok := outputs[name]
+	if !ok {
+		return nil, fmt.Errorf("no output named %v is registered", name)
+	}
+
+	return co(), nil
+}
+
+// joinBlocks renders each block with format and joins them the way i3bar
+// would lay them out, using " | " where i3bar would draw a separator line.
+// None of these outputs understand pango, so its markup is removed first.
+func joinBlocks(blocks []*types.Block, format func(*types.Block) string) string {
+	var sb strings.Builder
+	for i, b := range blocks {
+		if b.Markup == "pango" {
+			plain := *b
+			plain.FullText = stripPango(b.FullText)
+			plain.ShortText = stripPango(b.ShortText)
+			plain.Markup = ""
+			b = &plain
+		}
+		sb.WriteString(format(b))
+		if i == len(blocks)-1 {
+			break
+		}
+		switch {
+		case b.Separator == nil || *b.Separator:
+			sb.WriteString(" | ")
+		case b.SeparatorBlockWidth > 0:
+			sb.WriteString(" ")
+		}
+	}
+	return sb.String()
+}
+
+// pangoTag matches a pango markup tag, such as <span> or </b>
+var pangoTag = regexp.MustCompile(`</?[A-Za-z][^>]*>`)
+
+// stripPango returns pango markup as plain text
+func stripPango(text string) string {
+	return html.UnescapeString(pangoTag.ReplaceAllString(text, ""))
+}
+
+// parseColor returns the red, green and blue components of a #rrggbb color
+func parseColor(color string) (int, int, int, bool) {
+	if len(color) != 7 || color[0] != '#' {
+		return 0, 0, 0, false
+	}
+	v, err := strconv.ParseUint(color[1:], 16, 32)
+	if err != nil {
+		return 0, 0, 0, false
+	}
+	return int(v >> 16 & 0xff), int(v >> 8 & 0xff), int(v & 0xff), true
+}
@@ -0,0 +1,23 @@
+package outputs
+
+import (
+	"testing"
+
+	"github.com/travishegner/goi3status/types"
+)
+
+func TestJoinBlocksPango(t *testing.T) {
+	blocks := []*types.Block{
+		{FullText: `<span color="#ff0000">CPU</span> <b>5%</b> &lt;hot&gt;`, Markup: "pango"},
+		{FullText: "<not markup>"},
+	}
+
+	got := joinBlocks(blocks, func(b *types.Block) string { return b.FullText })
+	if want := "CPU 5% <hot> | <not markup>"; got != want {
+		t.Errorf("joinBlocks() = %q, want %q", got, want)
+	}
+	// the module's blocks are left alone
+	if blocks[0].Markup != "pango" {
+		t.Errorf("joinBlocks() modified the block")
+	}
+}
@@ -0,0 +1,49 @@
+package outputs
+
+import (
+	"strings"
+
+	"github.com/travishegner/goi3status/types"
+)
+
+func init() {
+	addOutputMap("lemonbar", NewLemonbar)
+	addOutputMap("polybar", NewLemonbar)
+}
+
+// Lemonbar is text with %{F#rrggbb} style formatting tags, as understood by lemonbar and polybar
+type Lemonbar struct{}
+
+// NewLemonbar returns the lemonbar output
+func NewLemonbar() types.Output {
+	return &Lemonbar{}
+}
+
+// Header returns nothing, lemonbar has no header
+func (o *Lemonbar) Header(c *types.Config) (string, error) {
+	return "", nil
+}
+
+// Frame returns the Block array as a single line of formatted text
+func (o *Lemonbar) Frame(blocks []*types.Block) (string, error) {
+	return joinBlocks(blocks, o.formatBlock), nil
+}
+
+func (o *Lemonbar) formatBlock(b *types.Block) string {
+	text := strings.ReplaceAll(b.FullText, "%", "%%")
+	if b.Color != "" {
+		text = "%{F" + b.Color + "}" + text + "%{F-}"
+	}
+	if b.Background != "" {
+		text = "%{B" + b.Background + "}" + text + "%{B-}"
+	}
+	if b.Urgent {
+		text = "%{R}" + text + "%{R}"
+	}
+	return text
+}
+
+// Footer returns nothing, lemonbar has no footer
+func (o *Lemonbar) Footer() string {
+	return ""
+}
@@ -0,0 +1,51 @@
+package outputs
+
+import (
+	"fmt"
+
+	"github.com/travishegner/goi3status/types"
+)
+
+func init() {
+	addOutputMap("term", NewTerm)
+}
+
+// Term is plain text colored with ANSI escape sequences, for terminals and remote sessions
+type Term struct{}
+
+// NewTerm returns the term output
+func NewTerm() types.Output {
+	return &Term{}
+}
+
+// Header returns nothing, terminals have no header
+func (o *Term) Header(c *types.Config) (string, error) {
+	return "", nil
+}
+
+// Frame returns the Block array as a single line of colored text
+func (o *Term) Frame(blocks []*types.Block) (string, error) {
+	return joinBlocks(blocks, o.formatBlock), nil
+}
+
+func (o *Term) formatBlock(b *types.Block) string {
+	sgr := ""
+	if r, g, bl, ok := parseColor(b.Color); ok {
+		sgr += fmt.Sprintf("\x1b[38;2;%d;%d;%dm", r, g, bl)
+	}
+	if r, g, bl, ok := parseColor(b.Background); ok {
+		sgr += fmt.Sprintf("\x1b[48;2;%d;%d;%dm", r, g, bl)
+	}
+	if b.Urgent {
+		sgr += "\x1b[7m"
+	}
+	if sgr == "" {
+		return b.FullText
+	}
+	return sgr + b.FullText + "\x1b[0m"
+}
+
+// Footer returns nothing, terminals have no footer
+func (o *Term) Footer() string {
+	return ""
+}
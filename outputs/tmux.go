@@ -0,0 +1,52 @@
+package outputs
+
+import (
+	"strings"
+
+	"github.com/travishegner/goi3status/types"
+)
+
+func init() {
+	addOutputMap("tmux", NewTmux)
+}
+
+// Tmux is text with #[fg=#rrggbb] style formatting, for use in a tmux status line
+type Tmux struct{}
+
+// NewTmux returns the tmux output
+func NewTmux() types.Output {
+	return &Tmux{}
+}
+
+// Header returns nothing, tmux has no header
+func (o *Tmux) Header(c *types.Config) (string, error) {
+	return "", nil
+}
+
+// Frame returns the Block array as a single line of formatted text
+func (o *Tmux) Frame(blocks []*types.Block) (string, error) {
+	return joinBlocks(blocks, o.formatBlock), nil
+}
+
+func (o *Tmux) formatBlock(b *types.Block) string {
+	text := strings.ReplaceAll(b.FullText, "#", "##")
+	style := make([]string, 0)
+	if b.Color != "" {
+		style = append(style, "fg="+b.Color)
+	}
+	if b.Background != "" {
+		style = append(style, "bg="+b.Background)
+	}
+	if b.Urgent {
+		style = append(style, "reverse")
+	}
+	if len(style) == 0 {
+		return text
+	}
+	return "#[" + strings.Join(style, ",") + "]" + text + "#[default]"
+}
+
+// Footer returns nothing, tmux has no footer
+func (o *Tmux) Footer() string {
+	return ""
+}
@@ -0,0 +1,60 @@
+package outputs
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/travishegner/goi3status/types"
+)
+
+func init() {
+	addOutputMap("xmobar", NewXmobar)
+}
+
+// Xmobar is text with <fc=#rrggbb> style formatting tags, as understood by xmobar's StdinReader
+type Xmobar struct{}
+
+// NewXmobar returns the xmobar output
+func NewXmobar() types.Output {
+	return &Xmobar{}
+}
+
+// Header returns nothing, xmobar has no header
+func (o *Xmobar) Header(c *types.Config) (string, error) {
+	return "", nil
+}
+
+// Frame returns the Block array as a single line of formatted text
+func (o *Xmobar) Frame(blocks []*types.Block) (string, error) {
+	return joinBlocks(blocks, o.formatBlock), nil
+}
+
+func (o *Xmobar) formatBlock(b *types.Block) string {
+	text := b.FullText
+	// xmobar has no escape character, but will display raw text verbatim,
+	// given its length in characters
+	if strings.ContainsAny(text, "<>") {
+		text = fmt.Sprintf("<raw=%d:%s/>", utf8.RuneCountInString(text), text)
+	}
+	fg := b.Color
+	bg := b.Background
+	if b.Urgent && bg == "" {
+		bg = "#ff0000"
+	}
+	if fg == "" && bg != "" {
+		fg = "#ffffff"
+	}
+	if bg != "" {
+		return "<fc=" + fg + "," + bg + ">" + text + "</fc>"
+	}
+	if fg != "" {
+		return "<fc=" + fg + ">" + text + "</fc>"
+	}
+	return text
+}
+
+// Footer returns nothing, xmobar has no footer
+func (o *Xmobar) Footer() string {
+	return ""
+}
@@ -3,6 +3,9 @@ package main
 import (
 	"encoding/json"
 	"fmt"
+	"os"
+	"reflect"
+	"sync"
 	"time"
 
 	log "github.com/sirupsen/logrus"
@@ -12,115 +15,320 @@ import (
 
 // Status represents the overall status bar
 type Status struct {
-	modules []types.Module
-	cache   [][]*types.Block
-	config  *types.Config
-	update  chan struct{}
-	done    chan struct{}
+	// mu guards entries, nextID, paused and minInterval, and the blocks of
+	// each entry
+	mu          sync.Mutex
+	entries     []*entry
+	nextID      int
+	paused      bool
+	minInterval time.Duration
+	config      *types.Config
+	output      types.Output
+	update      chan struct{}
+	done        chan struct{}
+	wg          sync.WaitGroup
 }
 
-// NewStatus returns an instance of Status
-func NewStatus(c *types.Config) *Status {
+// entry is a running module along with the config it was created from and
+// the last Block array it sent
+type entry struct {
+	// id identifies the entry in click events for as long as it runs
+	id     int
+	module types.Module
+	conf   map[interface{}]interface{}
+	blocks []*types.Block
+	stop   chan struct{}
+}
+
+// NewStatus returns an instance of Status which writes frames using output
+func NewStatus(c *types.Config, output types.Output) *Status {
+	s := &Status{
+		minInterval: c.GetMinInterval(),
+		config:      c,
+		output:      output,
+		update:      make(chan struct{}, 1),
+		done:        make(chan struct{}),
+	}
+
+	s.mu.Lock()
+	for _, m := range c.Modules {
+		mod, err := loadModule(m)
+		if err != nil {
+			log.Errorf("failed to load module: %v", err)
+			continue
+		}
+		s.entries = append(s.entries, s.startEntry(mod, m))
+	}
+	s.mu.Unlock()
+
+	s.wg.Add(1)
+	go s.render(s.done)
+	if c.ClickEvents {
+		go s.readClicks(os.Stdin)
+	}
+
+	return s
+}
+
+func loadModule(m map[interface{}]interface{}) (types.Module, error) {
+	name, ok := m["name"].(string)
+	if !ok {
+		return nil, fmt.Errorf("module name not defined")
+	}
+	mc, _ := m["config"].(map[interface{}]interface{})
+	mod, err := modules.GetModule(name, mc)
+	if err != nil {
+		return nil, fmt.Errorf("%v, %v", name, err)
+	}
+	return mod, nil
+}
+
+// startEntry wraps a module in an entry and starts forwarding its updates
+// to the renderer. The caller must hold s.mu.
+func (s *Status) startEntry(mod types.Module, conf map[interface{}]interface{}) *entry {
+	e := &entry{id: s.nextID, module: mod, conf: conf, stop: make(chan struct{})}
+	s.nextID++
+	s.wg.Add(1)
+	go s.forward(e)
+	return e
+}
+
+// forward fans in the updates from a single module, storing them in its
+// entry and waking the renderer
+func (s *Status) forward(e *entry) {
+	defer s.wg.Done()
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-e.stop:
+			return
+		case blocks := <-e.module.GetUpdateChan():
+			s.mu.Lock()
+			e.blocks = blocks
+			s.mu.Unlock()
+			s.notify()
+		}
+	}
+}
+
+// notify wakes the renderer without blocking; several notifications
+// arriving before the next frame collapse into one
+func (s *Status) notify() {
+	select {
+	case s.update <- struct{}{}:
+	default:
+	}
+}
+
+// Reload replaces the module list with the one in c, keeping modules whose
+// configuration is unchanged running (along with their state), stopping
+// removed modules and starting new ones
+func (s *Status) Reload(c *types.Config) {
+	if c.ClickEvents != s.config.ClickEvents || c.StopSignal != s.config.StopSignal || c.ContSignal != s.config.ContSignal || c.Output != s.config.Output {
+		log.Warnf("changes to output, click_events, stop_signal or cont_signal require a restart")
+	}
+
+	// modules are loaded without holding the lock, so work from a copy of
+	// the entry list; reloads are never run concurrently, so it can't
+	// change in the meantime
+	s.mu.Lock()
+	old := s.entries
+	s.mu.Unlock()
+
+	used := make([]bool, len(old))
+	kept := make([]*entry, 0)
 	mods := make([]types.Module, 0)
+	confs := make([]map[interface{}]interface{}, 0)
 	for _, m := range c.Modules {
-		name, ok := m["name"].(string)
-		if !ok {
-			log.Fatalf("module name not defined")
+		j := findEntry(old, m, used)
+		if j >= 0 {
+			used[j] = true
+			kept = append(kept, old[j])
+			mods = append(mods, nil)
+			confs = append(confs, m)
+			continue
 		}
-		mc, _ := m["config"].(map[interface{}]interface{})
-		mod, err := modules.GetModule(name, mc)
+
+		mod, err := loadModule(m)
 		if err != nil {
-			log.Errorf("failed to load module: %v, %v", name, err)
+			log.Errorf("failed to load module: %v", err)
 			continue
 		}
+		kept = append(kept, nil)
 		mods = append(mods, mod)
+		confs = append(confs, m)
 	}
-	cache := make([][]*types.Block, len(mods))
-	update := make(chan struct{})
-	done := make(chan struct{})
-	s := &Status{modules: mods, cache: cache, config: c, update: update, done: done}
 
-	s.updateCache()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for j, e := range old {
+		if !used[j] {
+			close(e.stop)
+			e.module.Stop()
+		}
+	}
 
-	go s.render(done)
-	go s.watchModules(done)
+	entries := make([]*entry, len(kept))
+	for i, e := range kept {
+		if e != nil {
+			entries[i] = e
+			continue
+		}
+		if s.paused {
+			mods[i].Pause()
+		}
+		entries[i] = s.startEntry(mods[i], confs[i])
+	}
+	s.entries = entries
+	s.minInterval = c.GetMinInterval()
+	s.notify()
+}
 
-	return s
+// findEntry returns the index of the first of entries which is not yet used
+// and has an identical configuration to m, or -1
+func findEntry(entries []*entry, m map[interface{}]interface{}, used []bool) int {
+	for j, e := range entries {
+		if !used[j] && reflect.DeepEqual(e.conf, m) {
+			return j
+		}
+	}
+	return -1
 }
 
 func (s *Status) render(done chan struct{}) {
-	j, err := json.Marshal(s.config)
+	defer s.wg.Done()
+	h, err := s.output.Header(s.config)
 	if err != nil {
-		log.Fatalf("error marshalling json: %v", err)
+		log.Fatalf("error rendering header: %v", err)
+	}
+	if h != "" {
+		s.write(h)
 	}
 
-	s.write(string(j))
-	s.write("[")
-
+	prev := ""
+	last := time.Time{}
 	for {
 		select {
 		case <-done:
 			return
 		case <-s.update:
-			j, err = json.Marshal(s.flattenCache())
-			if err != nil {
-				log.Errorf("failed to render status: %v", err)
+		}
+
+		// hold the frame back until the coalescing window has passed, so
+		// that bursts of updates from several modules render together
+		s.mu.Lock()
+		wait := time.Until(last.Add(s.minInterval))
+		s.mu.Unlock()
+		if wait > 0 {
+			select {
+			case <-done:
+				return
+			case <-time.After(wait):
+			}
+			// this frame includes anything which arrived while waiting
+			select {
+			case <-s.update:
+			default:
 			}
+		}
+
+		s.mu.Lock()
+		blocks := s.flattenCache()
+		j, err := json.Marshal(blocks)
+		s.mu.Unlock()
+		// modules often resend identical blocks, which needn't be redrawn
+		if err == nil && string(j) == prev {
+			continue
+		}
+		prev = string(j)
 
-			s.write(string(j) + ",")
+		f, err := s.output.Frame(blocks)
+		if err != nil {
+			log.Errorf("failed to render status: %v", err)
+			continue
 		}
-	}
 
+		s.write(f)
+		last = time.Now()
+	}
 }
 
 func (s *Status) write(line string) {
 	fmt.Printf("%v\n", line)
 }
 
+// flattenCache returns the blocks of every module in order. The caller must
+// hold s.mu.
 func (s *Status) flattenCache() []*types.Block {
 	f := make([]*types.Block, 0)
-	for _, ab := range s.cache {
-		for _, b := range ab {
-			f = append(f, b)
-		}
+	for _, e := range s.entries {
+		f = append(f, stampBlocks(e.id, e.blocks)...)
 	}
 	return f
 
 }
 
-func (s *Status) watchModules(done chan struct{}) {
-	for {
-		start := time.Now()
-		select {
-		case <-done:
-			return
-		default:
-			if s.updateCache() {
-				s.update <- struct{}{}
-			}
+// Signal refreshes every module configured with signal n
+func (s *Status) Signal(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, e := range s.entries {
+		mc, _ := e.conf["config"].(map[interface{}]interface{})
+		if sig, ok := mc["signal"].(int); ok && sig == n {
+			e.module.Refresh()
 		}
-		stop := time.Now()
-		elapsed := stop.Sub(start)
-		// this is effectively the maximum refresh rate of the status bar
-		// should this be configurable?
-		time.Sleep((100 * time.Millisecond) - elapsed)
 	}
 }
 
-func (s *Status) updateCache() bool {
-	update := false
-	for i, m := range s.modules {
-		select {
-		case blocks := <-m.GetUpdateChan():
-			s.cache[i] = blocks
-			update = true
-		default:
-		}
+// Pause suspends polling in every module, for when i3bar hides the bar
+func (s *Status) Pause() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.paused = true
+	for _, e := range s.entries {
+		e.module.Pause()
 	}
-	return update
 }
 
-// Stop closes the done channel which signals all modules to stop
-func (s *Status) Stop() {
+// Resume restarts polling in every module with an immediate refresh
+func (s *Status) Resume() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.paused = false
+	for _, e := range s.entries {
+		e.module.Resume()
+	}
+}
+
+// Stop closes the done channel, stops all modules and waits up to timeout
+// for everything to exit before writing the output's footer
+func (s *Status) Stop(timeout time.Duration) error {
 	close(s.done)
+	s.mu.Lock()
+	entries := s.entries
+	s.mu.Unlock()
+	for _, e := range entries {
+		e.module.Stop()
+	}
+
+	stopped := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		for _, e := range entries {
+			e.module.Wait()
+		}
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+	case <-time.After(timeout):
+		return fmt.Errorf("timed out after %v waiting for modules to stop", timeout)
+	}
+
+	if f := s.output.Footer(); f != "" {
+		s.write(f)
+	}
+	return nil
 }
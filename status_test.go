@@ -0,0 +1,60 @@
+package main
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/travishegner/goi3status/outputs"
+	"github.com/travishegner/goi3status/types"
+)
+
+// shellModules returns the config of n ShellCommand modules
+func shellModules(n int) []map[interface{}]interface{} {
+	mods := make([]map[interface{}]interface{}, 0)
+	for i := 0; i < n; i++ {
+		mods = append(mods, map[interface{}]interface{}{
+			"name":   "ShellCommand",
+			"config": map[interface{}]interface{}{"cmd": "echo", "signal": i},
+		})
+	}
+	return mods
+}
+
+func TestReloadConcurrently(t *testing.T) {
+	out, err := outputs.GetOutput("term")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := NewStatus(&types.Config{Output: "term", Modules: shellModules(2)}, out)
+
+	// signals, clicks and pausing all read the entry list while reloads
+	// replace it, which the race detector checks
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			s.Signal(1)
+			s.routeClick(&types.ClickEvent{Name: "1/0/", Button: 1})
+			s.Pause()
+			s.Resume()
+		}
+	}()
+
+	for i := 1; i < 20; i++ {
+		s.Reload(&types.Config{Output: "term", Modules: shellModules(i % 4)})
+	}
+	close(stop)
+	wg.Wait()
+
+	if err := s.Stop(5 * time.Second); err != nil {
+		t.Error(err)
+	}
+}
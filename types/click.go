@@ -0,0 +1,29 @@
+package types
+
+// ClickEvent is an i3bar click event as defined here: https://i3wm.org/docs/i3bar-protocol.html#_click_events
+type ClickEvent struct {
+	Name      string   `json:"name"`
+	Instance  string   `json:"instance"`
+	Button    int      `json:"button"`
+	Modifiers []string `json:"modifiers"`
+	X         int      `json:"x"`
+	Y         int      `json:"y"`
+	RelativeX int      `json:"relative_x"`
+	RelativeY int      `json:"relative_y"`
+	OutputX   int      `json:"output_x"`
+	OutputY   int      `json:"output_y"`
+	Width     int      `json:"width"`
+	Height    int      `json:"height"`
+	//the index of the clicked block within the module's Block array
+	Block int `json:"-"`
+}
+
+// HasModifier returns true if the named modifier (e.g. "Shift") was held during the click
+func (ce *ClickEvent) HasModifier(mod string) bool {
+	for _, m := range ce.Modifiers {
+		if m == mod {
+			return true
+		}
+	}
+	return false
+}
@@ -1,10 +1,23 @@
 package types
 
+import "time"
+
 // Config represents the whole status config
 type Config struct {
 	Version     int                           `yaml:"version" json:"version"`
 	StopSignal  int                           `yaml:"stop_signal" json:"stop_signal,omitempty"`
 	ContSignal  int                           `yaml:"cont_signal" json:"cont_signal,omitempty"`
 	ClickEvents bool                          `yaml:"click_events" json:"click_events,omitempty"`
+	Output      string                        `yaml:"output" json:"-"`
+	MinInterval *int                          `yaml:"min_interval" json:"-"`
 	Modules     []map[interface{}]interface{} `yaml:"modules" json:"-"`
 }
+
+// GetMinInterval returns the minimum time between frames, during which
+// module updates are coalesced (defaults to 100ms)
+func (c *Config) GetMinInterval() time.Duration {
+	if c.MinInterval == nil {
+		return 100 * time.Millisecond
+	}
+	return time.Duration(*c.MinInterval) * time.Millisecond
+}
@@ -1,6 +1,9 @@
 package types
 
-import "time"
+import (
+	"sync"
+	"time"
+)
 
 // ModuleConfig is the config of any given module, already unmarshalled
 type ModuleConfig map[interface{}]interface{}
@@ -12,13 +15,28 @@ type CreateModule func(ModuleConfig) Module
 type Module interface {
 	MakeBlocks() []*Block
 	GetUpdateChan() chan []*Block
+	Refresh()
+	Pause()
+	Resume()
 	Stop()
+	Wait()
+}
+
+// Clickable is implemented by modules which want to receive i3bar click events
+type Clickable interface {
+	Click(*ClickEvent)
 }
 
 // BaseModule contains the attributes common to all modules
 type BaseModule struct {
-	Update chan []*Block
-	Done   chan struct{}
+	Update  chan []*Block
+	Done    chan struct{}
+	pause   chan bool
+	refresh chan struct{}
+	wg      sync.WaitGroup
+	// resumed is closed while the module isn't paused
+	mu      sync.Mutex
+	resumed chan struct{}
 }
 
 // BaseModuleConfig contains the attributes common to all module configs
@@ -28,6 +46,7 @@ type BaseModuleConfig struct {
 	FinalSeparator      bool
 	FinalSeparatorWidth int
 	BlockSeparatorWidth int
+	Thresholds          *Thresholds
 }
 
 // NewBaseModuleConfig parses and returns a BaseModuleConfig
@@ -63,6 +82,7 @@ func NewBaseModuleConfig(mc ModuleConfig) *BaseModuleConfig {
 		FinalSeparator:      fseparator,
 		FinalSeparatorWidth: fsepWidth,
 		BlockSeparatorWidth: bsepWidth,
+		Thresholds:          NewThresholds(mc),
 	}
 }
 
@@ -70,8 +90,138 @@ func NewBaseModuleConfig(mc ModuleConfig) *BaseModuleConfig {
 func NewBaseModule() *BaseModule {
 	done := make(chan struct{})
 	update := make(chan []*Block, 1)
+	pause := make(chan bool, 1)
+	refresh := make(chan struct{}, 1)
+	resumed := make(chan struct{})
+	close(resumed)
 	return &BaseModule{
-		Update: update,
-		Done:   done,
+		Update:  update,
+		Done:    done,
+		pause:   pause,
+		refresh: refresh,
+		resumed: resumed,
+	}
+}
+
+// Run sends an initial Block array, then calls makeBlocks and sends the
+// result every refresh, or whenever Refresh is called, until the module is
+// stopped
+func (bm *BaseModule) Run(refresh time.Duration, makeBlocks func() []*Block) {
+	bm.Update <- makeBlocks()
+	ticker := time.NewTicker(refresh)
+	paused := false
+
+	bm.wg.Add(1)
+	go func() {
+		defer bm.wg.Done()
+		defer ticker.Stop()
+		for {
+			select {
+			case <-bm.Done:
+				return
+			case p := <-bm.pause:
+				paused = p
+				if p {
+					ticker.Stop()
+					// discard a tick which may have fired before we stopped
+					select {
+					case <-ticker.C:
+					default:
+					}
+					continue
+				}
+				ticker.Reset(refresh)
+				if !bm.Send(makeBlocks()) {
+					return
+				}
+			case <-ticker.C:
+				if !bm.Send(makeBlocks()) {
+					return
+				}
+			case <-bm.refresh:
+				if paused {
+					continue
+				}
+				if !bm.Send(makeBlocks()) {
+					return
+				}
+			}
+		}
+	}()
+}
+
+// Send sends a Block array down the update channel, returning false
+// instead of blocking forever if the module is stopped first
+func (bm *BaseModule) Send(blocks []*Block) bool {
+	select {
+	case bm.Update <- blocks:
+		return true
+	case <-bm.Done:
+		return false
+	}
+}
+
+// Refresh asks the module to send a fresh Block array now, rather than at
+// the next tick. It is ignored while the module is paused.
+func (bm *BaseModule) Refresh() {
+	select {
+	case bm.refresh <- struct{}{}:
+	default:
+	}
+}
+
+// Go runs f in a goroutine which Wait also waits for, so that a module's
+// own goroutines (e.g. watching a child process) can finish cleaning up
+// after Stop
+func (bm *BaseModule) Go(f func()) {
+	bm.wg.Add(1)
+	go func() {
+		defer bm.wg.Done()
+		f()
+	}()
+}
+
+// Wait blocks until the module's goroutines have exited
+func (bm *BaseModule) Wait() {
+	bm.wg.Wait()
+}
+
+// Pause stops the module from polling until Resume is called
+func (bm *BaseModule) Pause() {
+	bm.setPaused(true)
+}
+
+// Resume restarts polling and immediately sends a fresh Block array
+func (bm *BaseModule) Resume() {
+	bm.setPaused(false)
+}
+
+// Resumed returns a channel which is closed while the module isn't paused,
+// so that a module's own goroutines can wait for Resume
+func (bm *BaseModule) Resumed() <-chan struct{} {
+	bm.mu.Lock()
+	defer bm.mu.Unlock()
+	return bm.resumed
+}
+
+func (bm *BaseModule) setPaused(p bool) {
+	bm.mu.Lock()
+	select {
+	case <-bm.resumed:
+		if p {
+			bm.resumed = make(chan struct{})
+		}
+	default:
+		if !p {
+			close(bm.resumed)
+		}
+	}
+	bm.mu.Unlock()
+
+	// only the latest request matters, so replace any pending one
+	select {
+	case <-bm.pause:
+	default:
 	}
+	bm.pause <- p
 }
@@ -0,0 +1,14 @@
+package types
+
+// CreateOutput is a type to represent a generic "NewOutput" function
+type CreateOutput func() Output
+
+// Output turns Block arrays into the text a particular status bar program expects on stdin
+type Output interface {
+	// Header returns anything which must be written before the first frame, or ""
+	Header(*Config) (string, error)
+	// Frame returns a single update of the whole status line
+	Frame([]*Block) (string, error)
+	// Footer returns anything which must be written after the last frame, or ""
+	Footer() string
+}
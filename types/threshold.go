@@ -0,0 +1,258 @@
+package types
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+)
+
+// named colors which may be used in place of #rrggbb in threshold stops
+var namedColors = map[string]string{
+	"good":   "#00ff00",
+	"warn":   "#ffff00",
+	"crit":   "#ff0000",
+	"green":  "#00ff00",
+	"yellow": "#ffff00",
+	"orange": "#ff8000",
+	"red":    "#ff0000",
+	"blue":   "#0000ff",
+	"white":  "#ffffff",
+	"grey":   "#808080",
+}
+
+// Stop is a single point on a Thresholds scale
+type Stop struct {
+	// At is the percentage of the module's range where this stop begins
+	At     float64
+	Color  string
+	Urgent bool
+}
+
+// Thresholds maps a module's values onto colors and the urgent flag
+type Thresholds struct {
+	Stops []*Stop
+	// Interpolation is one of "step", "rgb" or "hsl"
+	Interpolation string
+	// Invert flips the scale, for values where low is bad (like battery charge)
+	Invert bool
+	// Hysteresis is how far (in percent) a value must fall back below a stop
+	// before the previous stop becomes active again
+	Hysteresis float64
+	// the active stop for each key, used for hysteresis
+	active map[string]int
+}
+
+// NewThresholds parses the thresholds, interpolation, invert and
+// hysteresis keys of a module config. Without any stops, the scale goes from
+// green, through yellow, to red.
+func NewThresholds(mc ModuleConfig) *Thresholds {
+	stops := make([]*Stop, 0)
+	raw, _ := mc["thresholds"].([]interface{})
+	for _, r := range raw {
+		m, ok := r.(map[interface{}]interface{})
+		if !ok {
+			continue
+		}
+		at, ok := toFloat(m["at"])
+		if !ok {
+			continue
+		}
+		color, _ := m["color"].(string)
+		urgent, _ := m["urgent"].(bool)
+		stops = append(stops, &Stop{At: at, Color: resolveColor(color), Urgent: urgent})
+	}
+
+	interp, ok := mc["interpolation"].(string)
+	if !ok {
+		interp = "rgb"
+	}
+
+	if len(stops) == 0 {
+		stops = []*Stop{
+			{At: 0, Color: namedColors["good"]},
+			{At: 50, Color: namedColors["warn"]},
+			{At: 100, Color: namedColors["crit"]},
+		}
+	}
+	sort.SliceStable(stops, func(i, j int) bool { return stops[i].At < stops[j].At })
+
+	invert, ok := mc["invert"].(bool)
+	if !ok {
+		invert = false
+	}
+
+	hyst, ok := toFloat(mc["hysteresis"])
+	if !ok {
+		hyst = 0
+	}
+
+	return &Thresholds{
+		Stops:         stops,
+		Interpolation: interp,
+		Invert:        invert,
+		Hysteresis:    hyst,
+		active:        make(map[string]int),
+	}
+}
+
+// Apply sets the color and urgent flag of block for a value between 0 and 1.
+// key identifies the value being tracked (e.g. a cpu core) for hysteresis.
+func (t *Thresholds) Apply(block *Block, key string, ratio float64) {
+	color, urgent := t.Get(key, ratio)
+	block.Color = color
+	block.Urgent = block.Urgent || urgent
+}
+
+// Get returns the color and urgent flag for a value between 0 and 1
+func (t *Thresholds) Get(key string, ratio float64) (string, bool) {
+	if math.IsNaN(ratio) {
+		ratio = 0
+	}
+	if t.Invert {
+		ratio = 1 - ratio
+	}
+	pct := math.Max(0, math.Min(100, ratio*100))
+
+	i := t.activeStop(key, pct)
+	stop := t.Stops[i]
+	if t.Interpolation == "step" || i == len(t.Stops)-1 || pct < stop.At {
+		return stop.Color, stop.Urgent
+	}
+
+	next := t.Stops[i+1]
+	frac := (pct - stop.At) / (next.At - stop.At)
+	if frac > 1 {
+		// hysteresis kept us on this stop while past the next one
+		frac = 1
+	}
+	switch t.Interpolation {
+	case "hsl":
+		return mixHSL(stop.Color, next.Color, frac), stop.Urgent
+	default:
+		return mixRGB(stop.Color, next.Color, frac), stop.Urgent
+	}
+}
+
+// activeStop returns the index of the highest stop at or below pct, only
+// moving down from the previously active stop once pct has dropped by more
+// than the hysteresis
+func (t *Thresholds) activeStop(key string, pct float64) int {
+	i := 0
+	for j, s := range t.Stops {
+		if pct >= s.At {
+			i = j
+		}
+	}
+
+	if prev, ok := t.active[key]; ok && prev > i && prev < len(t.Stops) {
+		if pct > t.Stops[prev].At-t.Hysteresis {
+			i = prev
+		}
+	}
+	t.active[key] = i
+	return i
+}
+
+func resolveColor(c string) string {
+	if n, ok := namedColors[c]; ok {
+		return n
+	}
+	return c
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case int:
+		return float64(n), true
+	case float64:
+		return n, true
+	case string:
+		f, err := strconv.ParseFloat(n, 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+func parseRGB(c string) (float64, float64, float64) {
+	if len(c) < 6 {
+		return 0, 0, 0
+	}
+	v, err := strconv.ParseUint(c[len(c)-6:], 16, 32)
+	if err != nil {
+		return 0, 0, 0
+	}
+	return float64(v >> 16 & 0xff), float64(v >> 8 & 0xff), float64(v & 0xff)
+}
+
+func formatRGB(r, g, b float64) string {
+	clamp := func(f float64) int {
+		return int(math.Max(0, math.Min(255, f)))
+	}
+	return fmt.Sprintf("#%0.2x%0.2x%0.2x", clamp(r), clamp(g), clamp(b))
+}
+
+func mixRGB(from, to string, frac float64) string {
+	r1, g1, b1 := parseRGB(from)
+	r2, g2, b2 := parseRGB(to)
+	return formatRGB(r1+(r2-r1)*frac, g1+(g2-g1)*frac, b1+(b2-b1)*frac)
+}
+
+func mixHSL(from, to string, frac float64) string {
+	h1, s1, l1 := rgbToHSL(parseRGB(from))
+	h2, s2, l2 := rgbToHSL(parseRGB(to))
+	// go around the hue circle the short way
+	dh := h2 - h1
+	if dh > 180 {
+		dh -= 360
+	} else if dh < -180 {
+		dh += 360
+	}
+	h := math.Mod(h1+dh*frac+360, 360)
+	return formatRGB(hslToRGB(h, s1+(s2-s1)*frac, l1+(l2-l1)*frac))
+}
+
+func rgbToHSL(r, g, b float64) (float64, float64, float64) {
+	r, g, b = r/255, g/255, b/255
+	max := math.Max(r, math.Max(g, b))
+	min := math.Min(r, math.Min(g, b))
+	l := (max + min) / 2
+	if max == min {
+		return 0, 0, l
+	}
+
+	d := max - min
+	s := d / (1 - math.Abs(2*l-1))
+	var h float64
+	switch max {
+	case r:
+		h = math.Mod((g-b)/d, 6)
+	case g:
+		h = (b-r)/d + 2
+	default:
+		h = (r-g)/d + 4
+	}
+	return math.Mod(h*60+360, 360), s, l
+}
+
+func hslToRGB(h, s, l float64) (float64, float64, float64) {
+	c := (1 - math.Abs(2*l-1)) * s
+	x := c * (1 - math.Abs(math.Mod(h/60, 2)-1))
+	m := l - c/2
+	var r, g, b float64
+	switch {
+	case h < 60:
+		r, g, b = c, x, 0
+	case h < 120:
+		r, g, b = x, c, 0
+	case h < 180:
+		r, g, b = 0, c, x
+	case h < 240:
+		r, g, b = 0, x, c
+	case h < 300:
+		r, g, b = x, 0, c
+	default:
+		r, g, b = c, 0, x
+	}
+	return (r + m) * 255, (g + m) * 255, (b + m) * 255
+}
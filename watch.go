@@ -0,0 +1,72 @@
+package main
+
+import (
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	log "github.com/sirupsen/logrus"
+)
+
+// editors often write a file in several steps, so wait for things to
+// settle before asking for a reload
+const watchSettle = 250 * time.Millisecond
+
+// watchConfig sends on reload whenever the file at path changes, without
+// blocking if a reload is already pending. The parent directory is watched,
+// since many editors replace the file rather than writing to it. If path is
+// a symlink, the directory of the file it points to is watched as well.
+func watchConfig(path string, reload chan<- struct{}) error {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+	target, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return err
+	}
+
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+
+	for _, dir := range []string{filepath.Dir(path), filepath.Dir(target)} {
+		if err := w.Add(dir); err != nil {
+			w.Close()
+			return err
+		}
+	}
+
+	go func() {
+		defer w.Close()
+		var settle <-chan time.Time
+		for {
+			select {
+			case ev, ok := <-w.Events:
+				if !ok {
+					return
+				}
+				if name := filepath.Clean(ev.Name); name != path && name != target {
+					continue
+				}
+				if ev.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Rename) != 0 {
+					settle = time.After(watchSettle)
+				}
+			case err, ok := <-w.Errors:
+				if !ok {
+					return
+				}
+				log.Errorf("error watching config file: %v", err)
+			case <-settle:
+				settle = nil
+				select {
+				case reload <- struct{}{}:
+				default:
+				}
+			}
+		}
+	}()
+
+	return nil
+}
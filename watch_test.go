@@ -0,0 +1,44 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWatchConfigSymlink(t *testing.T) {
+	dotfiles := t.TempDir()
+	target := filepath.Join(dotfiles, "goi3status.yaml")
+	if err := os.WriteFile(target, []byte("modules: []\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	link := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.Symlink(target, link); err != nil {
+		t.Fatal(err)
+	}
+
+	reload := make(chan struct{}, 1)
+	if err := watchConfig(link, reload); err != nil {
+		t.Fatal(err)
+	}
+
+	// several saves collapse into one pending reload, without blocking
+	for i := 0; i < 3; i++ {
+		if err := os.WriteFile(target, []byte("modules: []\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(2 * watchSettle)
+	}
+
+	select {
+	case <-reload:
+	case <-time.After(5 * time.Second):
+		t.Fatal("no reload after the symlinked config changed")
+	}
+	select {
+	case <-reload:
+		t.Error("reloads piled up")
+	default:
+	}
+}